@@ -23,6 +23,7 @@ type Instance struct {
 	Description          string                     `json:"description,omitempty"`
 	Definitions          json.RawMessage            `json:"definitions,omitempty"`
 	Embed                bool                       `json:"embed,omitempty"`
+	Emit                 json.RawMessage            `json:"emit,omitempty"` // Child documents of a fan-out transform, keyed by transform identifier
 	Format               string                     `json:"format,omitempty"`
 	FromRef              string                     `json:"fromRef,omitempty"`           // Added as a way of tracking the ref which was already expanded
 	GraphQLArguments     []string                   `json:"graphql-arguments,omitempty"` // For type="graphql-hydration" to also require query arguments.
@@ -34,6 +35,9 @@ type Instance struct {
 	Schema               string                     `json:"$schema,omitempty"`
 	Target               string                     `json:"target,omitempty"` // For type="graphql-hydration" or GraphQL schema type overrides.
 	Type                 []string                   `json:"type"`
+	XML                  json.RawMessage            `json:"xml,omitempty"` // XML object naming the element for XML output, as in OpenAPI
+
+	PropertyOrder []string `json:"-"` // The names of the Properties in the order they are declared
 }
 type instanceUnmarshal Instance
 
@@ -49,7 +53,18 @@ func (i *Instance) UnmarshalJSON(data []byte) error {
 			return fmt.Errorf("error changing schema type to array: %w", err)
 		}
 	}
-	return json.Unmarshal(data, (*instanceUnmarshal)(i))
+	if err := json.Unmarshal(data, (*instanceUnmarshal)(i)); err != nil {
+		return err
+	}
+
+	i.PropertyOrder = nil
+	if err := jsonparser.ObjectEach(data, func(key []byte, _ []byte, _ jsonparser.ValueType, _ int) error {
+		i.PropertyOrder = append(i.PropertyOrder, string(key))
+		return nil
+	}, "properties"); err != nil && !errors.Is(err, jsonparser.KeyPathNotFoundError) {
+		return fmt.Errorf("error reading schema properties order: %w", err)
+	}
+	return nil
 }
 
 // Schema represents a JSON Schema with the AllOf and OneOf references parsed and squashed into a single representation.
@@ -138,6 +153,7 @@ func schemaFromFile(schemaLoadPath string, oneOfType string, flatten bool) (*Sch
 			if !flatten && s.Properties != nil {
 				continue
 			}
+			s.PropertyOrder = mergePropertyOrder(s.PropertyOrder, all.PropertyOrder)
 			s.Properties = mergeProperties(s.Properties, all.Properties)
 			s.Required = append(s.Required, all.Required...)
 			if s.Items == nil {
@@ -151,6 +167,7 @@ func schemaFromFile(schemaLoadPath string, oneOfType string, flatten bool) (*Sch
 				continue
 			}
 
+			s.PropertyOrder = mergePropertyOrder(s.PropertyOrder, one.PropertyOrder)
 			s.Properties = mergeProperties(s.Properties, one.Properties)
 			s.Required = append(s.Required, one.Required...)
 			if s.Items == nil {
@@ -204,6 +221,21 @@ func mergeProperties(parent, child map[string]json.RawMessage) map[string]json.R
 	return newProperties
 }
 
+// mergePropertyOrder appends the names in the child order missing from the parent order, matching mergeProperties.
+func mergePropertyOrder(parent, child []string) []string {
+	seen := make(map[string]bool, len(parent))
+	for _, name := range parent {
+		seen[name] = true
+	}
+	for _, name := range child {
+		if !seen[name] {
+			parent = append(parent, name)
+			seen[name] = true
+		}
+	}
+	return parent
+}
+
 // FieldType returns the type name of a field and whether the field is nullable.
 func FieldType(data []byte) (string, bool, error) {
 	// The "type" field in JSON Schema is either a scalar or an array.
@@ -497,3 +497,34 @@ func TestSchemaTypes(t *testing.T) {
 		}
 	}
 }
+
+func TestSchemaPropertyOrder(t *testing.T) {
+	tests := []struct {
+		description string
+		oneOfType   string
+		schemaPath  string
+		want        []string
+	}{
+		{
+			description: "declared order",
+			schemaPath:  "./test_data/image.json",
+			want:        []string{"type", "crops", "URL"},
+		},
+		{
+			description: "allOf followed by oneOf",
+			oneOfType:   "subtype",
+			schemaPath:  "./test_data/allOf.json",
+			want:        []string{"aField", "bField", "cField", "dField", "eField", "fField", "gField", "hField"},
+		},
+	}
+
+	for _, test := range tests {
+		got, err := SchemaFromFile(test.schemaPath, test.oneOfType)
+		if err != nil {
+			t.Fatalf("Test %q - got error: %v", test.description, err)
+		}
+		if !reflect.DeepEqual(got.PropertyOrder, test.want) {
+			t.Errorf("Test %q - got %v, want %v", test.description, got.PropertyOrder, test.want)
+		}
+	}
+}
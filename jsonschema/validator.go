@@ -33,6 +33,18 @@ func NewValidator(schemaPath string) (*validator, error) {
 	}, nil
 }
 
+// NewInstanceValidator returns a Validator for a single schema instance given as raw JSON, for example a field from
+// WalkRaw. Any references in the instance must already be dereferenced.
+func NewInstanceValidator(raw json.RawMessage) (Validator, error) {
+	schema, err := gojsonschema.NewSchema(gojsonschema.NewBytesLoader(raw))
+	if err != nil {
+		return nil, err
+	}
+	return &validator{
+		schema: schema,
+	}, nil
+}
+
 func loadSchema(schemasDir string) (*gojsonschema.Schema, error) {
 	path, err := filepath.Abs(schemasDir)
 	if err != nil {
@@ -0,0 +1,135 @@
+package transform
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/antchfx/xmlquery"
+	"github.com/antchfx/xpath"
+)
+
+// callOperation is implemented by transformOperations which need the state of the Transform call they are part of,
+// for example the context or the registered Resolvers. When implemented transformWithCall is used rather than transform.
+type callOperation interface {
+	transformWithCall(call *transformCall, in interface{}) (interface{}, error)
+}
+
+// transformCall holds the state of a single Transform call which is shared by the instanceTransformers and
+// operations run as part of it. A nil *transformCall is valid and is used when transforming outside of a Transformer.
+type transformCall struct {
+	ctx       context.Context
+	resolvers map[string]Resolver
+	resolved  map[resolvedKey]interface{}
+	outputs   map[string]interface{} // output of the instances referenced with `$out.` paths keyed by path
+	vars      map[string]interface{} // variables referenced with `$vars.` paths
+	inputs    map[string]interface{} // named inputs referenced with `$inputs.` paths
+	clock     Clock                  // provides the time to currentTime
+	strict    bool                   // fail as soon as a required field has no value
+
+	emptyValues EmptyValuePolicy
+	metadata    *TransformMetadata
+
+	xmlNamespaces map[string]string
+	xpaths        map[string]*xpath.Expr // compiled XPath expressions which use the xmlNamespaces
+
+	varsOperations map[*varsOperation]map[string]initializedOperation // initialized operations keyed by their arguments
+}
+
+// CallOption sets an optional part of a single transform call, ie its variables, named inputs or metadata. The
+// options are passed to TransformContext and the other methods which take a context.
+type CallOption func(call *transformCall) error
+
+// newCall returns the state of a call of the Transformer with the context and the options applied.
+func (tr *Transformer) newCall(ctx context.Context, opts []CallOption) (*transformCall, error) {
+	call := newTransformCall(ctx, tr.args)
+	for _, opt := range opts {
+		if err := opt(call); err != nil {
+			return nil, err
+		}
+	}
+	if call.inputs != nil && tr.format != jsonInput {
+		return nil, errors.New("multiple inputs are only supported for JSON input")
+	}
+	return call, nil
+}
+
+// resolvedKey identifies a value cached from a Resolver.
+type resolvedKey struct {
+	resolver string
+	key      string
+}
+
+func newTransformCall(ctx context.Context, args TransformerArgs) *transformCall {
+	return &transformCall{
+		ctx:       ctx,
+		resolvers: args.Resolvers,
+		resolved:  make(map[resolvedKey]interface{}),
+		outputs:   make(map[string]interface{}),
+		clock:     newClock(ctx, args.Clock),
+		strict:    args.Strict,
+
+		emptyValues: args.EmptyValues,
+		metadata:    &TransformMetadata{},
+
+		xmlNamespaces: args.XMLNamespaces,
+		xpaths:        make(map[string]*xpath.Expr),
+
+		varsOperations: make(map[*varsOperation]map[string]initializedOperation),
+	}
+}
+
+// operate runs the operation on the value, passing along the call to operations which implement callOperation.
+func (call *transformCall) operate(op transformOperation, in interface{}) (interface{}, error) {
+	if cop, ok := op.(callOperation); ok {
+		return cop.transformWithCall(call, in)
+	}
+	return op.transform(in)
+}
+
+// resolve returns the value for the key from the named Resolver. Values are cached for the rest of the call so each
+// key is only looked up once per Transform.
+func (call *transformCall) resolve(name, key string) (interface{}, error) {
+	if call == nil {
+		return nil, errors.New("resolvers are only available when run by a Transformer")
+	}
+	resolver, ok := call.resolvers[name]
+	if !ok {
+		return nil, fmt.Errorf("no resolver registered with the name %q", name)
+	}
+
+	cacheKey := resolvedKey{resolver: name, key: key}
+	if value, ok := call.resolved[cacheKey]; ok {
+		return value, nil
+	}
+	if err := call.ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	value, err := resolver.Resolve(call.ctx, key)
+	if err != nil {
+		return nil, fmt.Errorf("resolver %q failed for key %q: %v", name, key, err)
+	}
+	call.resolved[cacheKey] = value
+	return value, nil
+}
+
+// findXML returns the nodes selected by the XPath expression. Namespace prefixes in the expression are resolved
+// using the XMLNamespaces of the Transformer, so they match the elements by namespace rather than by the prefix used
+// in the document.
+func (call *transformCall) findXML(top *xmlquery.Node, expr string) ([]*xmlquery.Node, error) {
+	if call == nil || len(call.xmlNamespaces) == 0 {
+		return xmlquery.QueryAll(top, expr)
+	}
+
+	selector, ok := call.xpaths[expr]
+	if !ok {
+		var err error
+		selector, err = xpath.CompileWithNS(expr, call.xmlNamespaces)
+		if err != nil {
+			return nil, err
+		}
+		call.xpaths[expr] = selector
+	}
+	return xmlquery.QuerySelectorAll(top, selector), nil
+}
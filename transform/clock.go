@@ -0,0 +1,53 @@
+package transform
+
+import (
+	"context"
+	"time"
+)
+
+// Clock provides the current time to the operations which depend on it, such as currentTime.
+type Clock interface {
+	Now() time.Time
+}
+
+// systemClock is the Clock used by default, it returns the time of the system.
+type systemClock struct{}
+
+func (systemClock) Now() time.Time { return time.Now() }
+
+// fixedClock is a Clock which always returns the same time.
+type fixedClock time.Time
+
+func (c fixedClock) Now() time.Time { return time.Time(c) }
+
+// FixedClock returns a Clock which always returns t, for tests and for replaying imports as of a past time.
+func FixedClock(t time.Time) Clock {
+	return fixedClock(t)
+}
+
+// clockKey is the context key of the Clock set with WithClock.
+type clockKey struct{}
+
+// WithClock returns a copy of the context which overrides the Clock of the Transformer for a TransformContext call.
+func WithClock(ctx context.Context, clock Clock) context.Context {
+	return context.WithValue(ctx, clockKey{}, clock)
+}
+
+// newClock returns the Clock set on the context, or else the Clock of the Transformer or else the system clock.
+func newClock(ctx context.Context, clock Clock) Clock {
+	if ctxClock, ok := ctx.Value(clockKey{}).(Clock); ok && ctxClock != nil {
+		return ctxClock
+	}
+	if clock != nil {
+		return clock
+	}
+	return systemClock{}
+}
+
+// now returns the current time of the Clock used for the call.
+func (call *transformCall) now() time.Time {
+	if call == nil {
+		return time.Now()
+	}
+	return call.clock.Now()
+}
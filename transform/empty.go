@@ -0,0 +1,129 @@
+package transform
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/GannettDigital/jsonparser"
+	"github.com/GannettDigital/jstransform/jsonschema"
+)
+
+// EmptyValuePolicy controls how fields without a value or with an empty value are written in the output. By default
+// fields without a value are omitted and empty values are written as is. Settings which write null only apply to
+// nullable fields, those with a type such as ["string", "null"], as null would otherwise fail validation.
+type EmptyValuePolicy struct {
+	EmitNull         bool // Nullable fields without a value are written as null rather than omitted
+	OmitEmpty        bool // Empty strings, arrays and objects are treated as having no value
+	EmptyArrayAsNull bool // Empty arrays are written as null for nullable fields
+}
+
+// fieldEmptyValues holds the settings from the `emptyValues` object of a field which override the EmptyValuePolicy
+// of the Transformer, along with whether the field is nullable.
+type fieldEmptyValues struct {
+	nullable         bool
+	EmitNull         *bool `json:"emitNull"`
+	OmitEmpty        *bool `json:"omitEmpty"`
+	EmptyArrayAsNull *bool `json:"emptyArrayAsNull"`
+}
+
+// newFieldEmptyValues reads the nullable type and `emptyValues` object of the field schema.
+func newFieldEmptyValues(raw json.RawMessage) (*fieldEmptyValues, error) {
+	_, nullable, err := jsonschema.FieldType(raw)
+	if err != nil {
+		return nil, fmt.Errorf("failed to extract instance type: %v", err)
+	}
+
+	fev := &fieldEmptyValues{nullable: nullable}
+	rawEmptyValues, _, _, err := jsonparser.Get(raw, "emptyValues")
+	if err != nil && err != jsonparser.KeyPathNotFoundError {
+		return nil, fmt.Errorf("failed to extract emptyValues: %v", err)
+	}
+	if len(rawEmptyValues) != 0 {
+		if err := json.Unmarshal(rawEmptyValues, fev); err != nil {
+			return nil, fmt.Errorf("failed to parse emptyValues: %v", err)
+		}
+	}
+	return fev, nil
+}
+
+// policy returns the EmptyValuePolicy of the Transformer with the settings of the field applied.
+func (fev *fieldEmptyValues) policy(base EmptyValuePolicy) EmptyValuePolicy {
+	if fev == nil {
+		return base
+	}
+	if fev.EmitNull != nil {
+		base.EmitNull = *fev.EmitNull
+	}
+	if fev.OmitEmpty != nil {
+		base.OmitEmpty = *fev.OmitEmpty
+	}
+	if fev.EmptyArrayAsNull != nil {
+		base.EmptyArrayAsNull = *fev.EmptyArrayAsNull
+	}
+	return base
+}
+
+// apply returns the value to save for the field and true when an explicit null is written instead.
+func (fev *fieldEmptyValues) apply(call *transformCall, value interface{}) (interface{}, bool) {
+	policy := fev.policy(call.emptyValuePolicy())
+	nullable := fev != nil && fev.nullable
+
+	if array, ok := value.([]interface{}); ok && len(array) == 0 && policy.EmptyArrayAsNull && nullable {
+		return nil, true
+	}
+	if policy.OmitEmpty && isEmpty(value) {
+		value = nil
+	}
+	if value == nil && policy.EmitNull && nullable {
+		return nil, true
+	}
+	return value, false
+}
+
+// saveNull writes the field as null in the object when it is nullable, otherwise it is omitted as null would fail
+// validation.
+func (fev *fieldEmptyValues) saveNull(object map[string]interface{}, name string) {
+	if fev != nil && fev.nullable {
+		object[name] = nil
+	}
+}
+
+// keepEmptyArray reports if an empty array is returned rather than nil by the array of the field so it is written
+// as null.
+func (fev *fieldEmptyValues) keepEmptyArray(call *transformCall) bool {
+	if fev == nil || !fev.nullable {
+		return false
+	}
+	return fev.policy(call.emptyValuePolicy()).EmptyArrayAsNull
+}
+
+// emptyValuePolicy returns the EmptyValuePolicy of the Transformer running the call.
+func (call *transformCall) emptyValuePolicy() EmptyValuePolicy {
+	if call == nil {
+		return EmptyValuePolicy{}
+	}
+	return call.emptyValues
+}
+
+// isEmpty reports if the value is an empty string, array or object.
+func isEmpty(value interface{}) bool {
+	switch v := value.(type) {
+	case string:
+		return v == ""
+	case []interface{}:
+		return len(v) == 0
+	case map[string]interface{}:
+		return len(v) == 0
+	}
+	return false
+}
+
+// hasValue reports if any field of the object has a value other than an explicit null.
+func hasValue(object map[string]interface{}) bool {
+	for _, value := range object {
+		if value != nil {
+			return true
+		}
+	}
+	return false
+}
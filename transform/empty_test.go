@@ -0,0 +1,82 @@
+package transform
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/GannettDigital/jstransform/jsonschema"
+)
+
+func TestEmptyValues(t *testing.T) {
+	schema, err := jsonschema.SchemaFromFile("./test_data/empty-values.json", "")
+	if err != nil {
+		t.Fatalf("failed to load schema: %v", err)
+	}
+
+	in := json.RawMessage(`{"title": "", "subtitle": "Sub", "tags": [], "keywords": [], "caption": ""}`)
+
+	tests := []struct {
+		description string
+		policy      EmptyValuePolicy
+		in          json.RawMessage
+		want        json.RawMessage
+	}{
+		{
+			description: "default",
+			in:          in,
+			want:        json.RawMessage(`{"subtitle":"Sub","title":""}`),
+		},
+		{
+			description: "emit null",
+			policy:      EmptyValuePolicy{EmitNull: true},
+			in:          in,
+			want:        json.RawMessage(`{"byline":null,"subtitle":"Sub","tags":null,"title":""}`),
+		},
+		{
+			description: "omit empty",
+			policy:      EmptyValuePolicy{OmitEmpty: true},
+			in:          in,
+			want:        json.RawMessage(`{"subtitle":"Sub"}`),
+		},
+		{
+			description: "empty array as null",
+			policy:      EmptyValuePolicy{EmptyArrayAsNull: true},
+			in:          in,
+			want:        json.RawMessage(`{"subtitle":"Sub","tags":null,"title":""}`),
+		},
+		{
+			description: "empty array as null with no array",
+			policy:      EmptyValuePolicy{EmptyArrayAsNull: true},
+			in:          json.RawMessage(`{"title": "Title"}`),
+			want:        json.RawMessage(`{"title":"Title"}`),
+		},
+		{
+			description: "emit null within an object",
+			policy:      EmptyValuePolicy{EmitNull: true},
+			in:          json.RawMessage(`{"title": "Title", "byline": {"email": "jane@example.com"}, "summary": "Summary"}`),
+			want:        json.RawMessage(`{"byline":null,"subtitle":null,"summary":"Summary","tags":null,"title":"Title"}`),
+		},
+		{
+			description: "field settings override the transformer",
+			policy:      EmptyValuePolicy{EmitNull: true, OmitEmpty: true},
+			in:          json.RawMessage(`{"title": "Title", "summary": ""}`),
+			want:        json.RawMessage(`{"byline":null,"subtitle":null,"tags":null,"title":"Title"}`),
+		},
+	}
+
+	for _, test := range tests {
+		tr, err := NewTransformerWithArgs(schema, "cumulo", TransformerArgs{EmptyValues: test.policy})
+		if err != nil {
+			t.Fatalf("Test %q - failed to initialize transformer: %v", test.description, err)
+		}
+		got, err := tr.Transform(test.in)
+		if err != nil {
+			t.Errorf("Test %q - got error, want nil: %v", test.description, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("Test %q - got\n%s\nwant\n%s", test.description, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,243 @@
+package transform
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	jsonpath "github.com/GannettDigital/PaesslerAG_jsonpath"
+	"github.com/GannettDigital/jsonparser"
+	"github.com/GannettDigital/jstransform/jsonschema"
+)
+
+// RootDocument is the key of the document transformed with the root schema in the output of TransformFanOut.
+const RootDocument = "$"
+
+// emitNameRe matches the names usable for the documents of an emit instruction.
+var emitNameRe = regexp.MustCompile(`^[\w-]+$`)
+
+// emitInstruction is an entry of the `emit` section of a schema, it selects part of the input to be transformed into
+// separate documents with a child schema.
+type emitInstruction struct {
+	Name     string          `json:"name"`
+	Schema   json.RawMessage `json:"schema"`
+	JSONPath string          `json:"jsonPath"`
+	Key      string          `json:"key"`
+}
+
+// emitter produces the documents of an emit instruction.
+type emitter struct {
+	name        string
+	jsonPath    string
+	key         string // jsonPath within each transformed document of its key, the index is used when empty
+	transformer *Transformer
+}
+
+// newEmitters returns an emitter for each of the emit instructions of the schema for the first of the
+// transformIdentifiers which has them. Each child schema is usually a `$ref` to another schema file.
+func newEmitters(schema *jsonschema.Schema, transformIdentifiers []string, format inputFormat, args TransformerArgs) ([]*emitter, error) {
+	if len(schema.Emit) == 0 {
+		return nil, nil
+	}
+
+	var rawInstructions []byte
+	for _, transformIdentifier := range transformIdentifiers {
+		var err error
+		rawInstructions, _, _, err = jsonparser.Get(schema.Emit, transformIdentifier)
+		if err != nil && err != jsonparser.KeyPathNotFoundError {
+			return nil, fmt.Errorf("failed to extract the emit instructions: %v", err)
+		}
+		if len(rawInstructions) != 0 {
+			break
+		}
+	}
+	if len(rawInstructions) == 0 {
+		return nil, nil
+	}
+
+	var instructions []emitInstruction
+	if err := json.Unmarshal(rawInstructions, &instructions); err != nil {
+		return nil, fmt.Errorf("failed to parse the emit instructions: %v", err)
+	}
+
+	// Child documents are transformed on their own, the mapping is only for the root schema.
+	childArgs := args
+	childArgs.Mapping = nil
+
+	emitters := make([]*emitter, 0, len(instructions))
+	names := make(map[string]bool, len(instructions))
+	for _, instruction := range instructions {
+		if !emitNameRe.MatchString(instruction.Name) {
+			return nil, fmt.Errorf("invalid emit name %q, must be letters, digits, '_' or '-'", instruction.Name)
+		}
+		if names[instruction.Name] {
+			return nil, fmt.Errorf("duplicate emit name %q", instruction.Name)
+		}
+		names[instruction.Name] = true
+
+		if instruction.JSONPath == "" {
+			instruction.JSONPath = "$"
+		}
+		if !strings.HasPrefix(instruction.JSONPath, "$") {
+			return nil, fmt.Errorf("invalid jsonPath %q for emit %q, must start with '$'", instruction.JSONPath, instruction.Name)
+		}
+		if instruction.Key != "" && !strings.HasPrefix(instruction.Key, "$") {
+			return nil, fmt.Errorf("invalid key %q for emit %q, must start with '$'", instruction.Key, instruction.Name)
+		}
+
+		childSchema, err := instanceSchema(instruction.Schema)
+		if err != nil {
+			return nil, fmt.Errorf("invalid schema for emit %q: %v", instruction.Name, err)
+		}
+		child, err := newTransformer(childSchema, transformIdentifiers[0], format, childArgs)
+		if err != nil {
+			return nil, fmt.Errorf("failed initializing the transformer for emit %q: %v", instruction.Name, err)
+		}
+
+		emitters = append(emitters, &emitter{
+			name:        instruction.Name,
+			jsonPath:    instruction.JSONPath,
+			key:         instruction.Key,
+			transformer: child,
+		})
+	}
+	return emitters, nil
+}
+
+// instanceSchema returns a Schema for the raw dereferenced schema of an emit instruction. Emit instructions within
+// the child schema are ignored as only a single level of documents is emitted.
+func instanceSchema(raw json.RawMessage) (*jsonschema.Schema, error) {
+	if len(raw) == 0 {
+		return nil, errors.New("no schema given")
+	}
+	instance := jsonschema.Instance{AdditionalProperties: true}
+	if err := json.Unmarshal(raw, &instance); err != nil {
+		return nil, fmt.Errorf("failed to parse schema: %v", err)
+	}
+	instance.Emit = nil
+
+	validator, err := jsonschema.NewInstanceValidator(raw)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize schema validator: %v", err)
+	}
+	return &jsonschema.Schema{Instance: instance, Validator: validator}, nil
+}
+
+// TransformFanOut transforms the input with the root schema and with the child schema of each of the schema's `emit`
+// instructions, returning the documents keyed by RootDocument for the root schema and by the emit name for the
+// children. An emit instruction whose jsonPath has a `[*]` produces a document for each item, keyed by the emit name
+// and the value of its key within the transformed document, ie `gallery/1234`, or its index when it has no key.
+// Each document is validated against its own schema. This is only supported for JSON input.
+func (tr *Transformer) TransformFanOut(raw json.RawMessage) (map[string]json.RawMessage, error) {
+	return tr.TransformFanOutContext(context.Background(), raw)
+}
+
+// TransformFanOutContext is the same as TransformFanOut but the context and options are used for the transform of
+// every document, as with TransformContext.
+func (tr *Transformer) TransformFanOutContext(ctx context.Context, raw json.RawMessage, opts ...CallOption) (map[string]json.RawMessage, error) {
+	if tr.format != jsonInput {
+		return nil, errors.New("fan-out transforms are only supported for JSON input")
+	}
+
+	in, err := decodeJSON(raw)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse input JSON: %v", err)
+	}
+
+	call, err := tr.newCall(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	root, err := tr.transformDecoded(call, in)
+	if err != nil {
+		return nil, err
+	}
+	if root, err = tr.encodeOutput(tr.validateJSON(root)); err != nil {
+		return nil, err
+	}
+
+	documents := map[string]json.RawMessage{RootDocument: root}
+	for _, e := range tr.emitters {
+		if err := e.emit(ctx, opts, in, documents); err != nil {
+			return nil, fmt.Errorf("failed to emit %q: %v", e.name, err)
+		}
+	}
+	return documents, nil
+}
+
+// emit transforms the parts of the input selected by the emitter adding them to the documents, each in a call with
+// the context and options. Nothing is emitted when the jsonPath selects no value.
+func (e *emitter) emit(ctx context.Context, opts []CallOption, in interface{}, documents map[string]json.RawMessage) error {
+	selected, err := jsonpath.Get(e.jsonPath, in)
+	if err != nil || selected == nil {
+		return nil
+	}
+
+	items := []interface{}{selected}
+	multiple := strings.Contains(e.jsonPath, "[*]")
+	if multiple {
+		var ok bool
+		if items, ok = selected.([]interface{}); !ok {
+			items = []interface{}{selected}
+		}
+	}
+
+	for i, item := range items {
+		tr := e.transformer
+		call, err := tr.newCall(ctx, opts)
+		if err != nil {
+			return err
+		}
+		transformed, err := tr.transformDecoded(call, item)
+		if err != nil {
+			return fmt.Errorf("document %d: %v", i, err)
+		}
+		if transformed, err = tr.validateJSON(transformed); err != nil {
+			return fmt.Errorf("document %d: %v", i, err)
+		}
+
+		name := e.name
+		switch {
+		case e.key != "":
+			key, err := documentKey(transformed, e.key)
+			if err != nil {
+				return fmt.Errorf("document %d: %v", i, err)
+			}
+			name += "/" + key
+		case multiple:
+			name += "/" + strconv.Itoa(i)
+		}
+		if _, ok := documents[name]; ok {
+			return fmt.Errorf("duplicate document %q", name)
+		}
+
+		if documents[name], err = tr.encodeOutput(transformed, nil); err != nil {
+			return fmt.Errorf("document %d: %v", i, err)
+		}
+	}
+	return nil
+}
+
+// documentKey returns the value at the jsonPath of the key within the transformed document as a string.
+func documentKey(transformed json.RawMessage, keyPath string) (string, error) {
+	document, err := decodeJSON(transformed)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse transformed JSON: %v", err)
+	}
+	value, err := jsonpath.Get(keyPath, document)
+	if err != nil || value == nil {
+		return "", fmt.Errorf("no value for the key %q", keyPath)
+	}
+	key, err := convertString(value)
+	if err != nil {
+		return "", fmt.Errorf("invalid value for the key %q: %v", keyPath, err)
+	}
+	if key == "" {
+		return "", fmt.Errorf("empty value for the key %q", keyPath)
+	}
+	return key.(string), nil
+}
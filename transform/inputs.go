@@ -0,0 +1,77 @@
+package transform
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	jsonpath "github.com/GannettDigital/PaesslerAG_jsonpath"
+)
+
+const (
+	// inputsPrefix is the prefix of jsonPaths which read from the named inputs set with WithInputs.
+	inputsPrefix = "$inputs."
+
+	// PrimaryInput is the name of the input which jsonPaths starting with `$` select from, it is also used for fields
+	// without a transform. With WithInputs it is the input of the call.
+	PrimaryInput = "primary"
+)
+
+// TransformMulti is the same as Transform but builds the output from several named JSON documents, ie a story, the
+// metadata of its assets and a taxonomy record, see WithInputs. The input named PrimaryInput is transformed, it is
+// an empty object when there is none.
+func (tr *Transformer) TransformMulti(inputs map[string][]byte) (json.RawMessage, error) {
+	primary, ok := inputs[PrimaryInput]
+	if !ok {
+		primary = []byte(`{}`)
+	}
+	named := make(map[string][]byte, len(inputs))
+	for name, raw := range inputs {
+		if name != PrimaryInput {
+			named[name] = raw
+		}
+	}
+	return tr.TransformContext(context.Background(), primary, WithInputs(named))
+}
+
+// WithInputs adds named JSON documents to the call for transforms building the output from several documents.
+// Instructions select from an input by name with `$inputs.` paths, ie `$inputs.asset.title`, while other jsonPaths
+// select from the input of the call, which is also the input named PrimaryInput. The join operation combines the
+// items of arrays from different inputs by key. This is only supported for JSON input.
+func WithInputs(inputs map[string][]byte) CallOption {
+	decoded := make(map[string]interface{}, len(inputs))
+	var decodeErr error
+	for name, raw := range inputs {
+		in, err := decodeJSON(raw)
+		if err != nil {
+			decodeErr = fmt.Errorf("failed to parse input %q: %v", name, err)
+			break
+		}
+		decoded[name] = in
+	}
+
+	return func(call *transformCall) error {
+		if decodeErr != nil {
+			return decodeErr
+		}
+		// Each call has its own map as the PrimaryInput is added to it.
+		call.inputs = make(map[string]interface{}, len(decoded)+1)
+		for name, in := range decoded {
+			call.inputs[name] = in
+		}
+		return nil
+	}
+}
+
+// input returns the value at the jsonPath within the named inputs of the call or nil if there is none. The path is
+// the `$inputs.` path with the `$.` prefix, ie `$.asset.title`.
+func (call *transformCall) input(path string) interface{} {
+	if call == nil || call.inputs == nil {
+		return nil
+	}
+	value, err := jsonpath.Get(path, call.inputs)
+	if err != nil {
+		return nil
+	}
+	return value
+}
@@ -34,7 +34,7 @@ type instanceTransformer interface {
 	child() instanceTransformer // Arrays return a child object all others nil
 	path() string
 	selectChild(string) instanceTransformer // This returns nil for everything except objects
-	transform(interface{}, pathModifier) (interface{}, error)
+	transform(*transformCall, interface{}, pathModifier) (interface{}, error)
 }
 
 // arrayTransformer represents a JSON instance type array in the case of a JSON transform or an array of xmlquery.Node in the case of an XML transform.
@@ -45,16 +45,18 @@ type arrayTransformer struct {
 	jsonPath         string
 	format           inputFormat
 	transforms       *transformInstructions
+	xmlFallback      *transformInstruction // Selects the element at the same path as the field
+	emptyValues      *fieldEmptyValues     // Set when the array is a field of an object
 }
 
-func newArrayTransformer(path, transformIdentifier string, raw json.RawMessage, format inputFormat) (*arrayTransformer, error) {
+func newArrayTransformer(path string, transformIdentifiers []string, raw json.RawMessage, format inputFormat) (*arrayTransformer, error) {
 	at := &arrayTransformer{
 		jsonPath: path,
 		format:   format,
 	}
 
 	var err error
-	at.transforms, err = extractTransformInstructions(raw, transformIdentifier, path, "array")
+	at.transforms, err = extractTransformInstructions(raw, transformIdentifiers, path, "array")
 	if err != nil {
 		return nil, err
 	}
@@ -79,10 +81,10 @@ func (at *arrayTransformer) addChild(child instanceTransformer) error {
 	return nil
 }
 
-func (at *arrayTransformer) baseValueJSON(in interface{}, path string, modifier pathModifier) ([]interface{}, bool, error) {
+func (at *arrayTransformer) baseValueJSON(call *transformCall, in interface{}, path string, modifier pathModifier) ([]interface{}, bool, error) {
 	// 1. Use a transform if it exists
 	if at.transforms != nil {
-		rawValue, err := at.transforms.transform(in, "array", modifier, at.format)
+		rawValue, err := at.transforms.transform(call, in, "array", modifier, at.format)
 		if err != nil {
 			return nil, false, err
 		}
@@ -112,47 +114,66 @@ func (at *arrayTransformer) baseValueJSON(in interface{}, path string, modifier
 	return nil, false, nil
 }
 
-func (at *arrayTransformer) baseValueXML(in interface{}, path string, modifier pathModifier) ([]interface{}, bool, error) {
+func (at *arrayTransformer) baseValueXML(call *transformCall, in interface{}, path string, modifier pathModifier) ([]interface{}, bool, error) {
 	// 1. Use a transform if it exists
 	if at.transforms != nil {
-		rawValue, err := at.transforms.transform(in, "array", modifier, at.format)
+		rawValue, err := at.transforms.transform(call, in, "array", modifier, at.format)
 		if err != nil {
 			return nil, false, err
 		}
-
-		// if rawValue is an array of xml nodes we need to append them to newValue for return as []interface{}
-		xmlNodeArray, ok := rawValue.([]*xmlquery.Node)
-		if ok {
-			newValue := make([]interface{}, len(xmlNodeArray))
-			for i, item := range xmlNodeArray {
-				newValue[i] = item
-			}
-			return newValue, false, nil
+		if newValue, changed := xmlArrayValue(rawValue); newValue != nil {
+			return newValue, changed, nil
 		}
+	}
 
-		if rawValue != nil {
-			newValue, ok := rawValue.([]interface{})
-			if !ok {
-				newValue = []interface{}{rawValue}
-			}
-			return newValue, true, nil
+	// 2. Look for the elements at the same path relative to the current node.
+	if at.xmlFallback != nil {
+		rawValue, err := at.xmlFallback.transform(call, in, "array", nil, at.format)
+		if err != nil {
+			return nil, false, err
+		}
+		if newValue, changed := xmlArrayValue(rawValue); newValue != nil {
+			return newValue, changed, nil
 		}
 	}
 
-	// 2. Fall back to the JSON Schema default value.
+	// 3. Fall back to the JSON Schema default value.
 	if at.defaultValue != nil {
 		return at.defaultValue, true, nil
 	}
 	return nil, false, nil
 }
 
+// xmlArrayValue returns the array for a value found in XML input. Arrays of xml nodes are returned as is to be
+// transformed by the child, other values are returned along with true to indicate they were changed.
+func xmlArrayValue(rawValue interface{}) ([]interface{}, bool) {
+	// if rawValue is an array of xml nodes we need to append them to newValue for return as []interface{}
+	xmlNodeArray, ok := rawValue.([]*xmlquery.Node)
+	if ok {
+		newValue := make([]interface{}, len(xmlNodeArray))
+		for i, item := range xmlNodeArray {
+			newValue[i] = item
+		}
+		return newValue, false
+	}
+
+	if rawValue != nil {
+		newValue, ok := rawValue.([]interface{})
+		if !ok {
+			newValue = []interface{}{rawValue}
+		}
+		return newValue, true
+	}
+	return nil, false
+}
+
 // baseValue routes to the correct arrayTransformer.baseValue format.
-func (at *arrayTransformer) baseValue(in interface{}, path string, modifier pathModifier) ([]interface{}, bool, error) {
+func (at *arrayTransformer) baseValue(call *transformCall, in interface{}, path string, modifier pathModifier) ([]interface{}, bool, error) {
 	if at.format == jsonInput {
-		return at.baseValueJSON(in, path, modifier)
+		return at.baseValueJSON(call, in, path, modifier)
 	}
 	if at.format == xmlInput {
-		return at.baseValueXML(in, path, modifier)
+		return at.baseValueXML(call, in, path, modifier)
 	}
 	return nil, false, errors.New("unknown transform type in arrayTransformer baseValue")
 }
@@ -163,12 +184,12 @@ func (at *arrayTransformer) selectChild(key string) instanceTransformer { return
 
 // arrayTransformJSON retrieves the value for this object by building the value for the base object and then adding in any
 // transforms for all defined child fields.
-func (at *arrayTransformer) arrayTransformJSON(in interface{}, modifier pathModifier) (interface{}, error) {
+func (at *arrayTransformer) arrayTransformJSON(call *transformCall, in interface{}, modifier pathModifier) (interface{}, error) {
 	path := at.jsonPath
 	if modifier != nil {
 		path = modifier(path)
 	}
-	base, changed, err := at.baseValue(in, path, modifier)
+	base, changed, err := at.baseValue(call, in, path, modifier)
 	if err != nil {
 		return nil, err
 	}
@@ -198,7 +219,10 @@ func (at *arrayTransformer) arrayTransformJSON(in interface{}, modifier pathModi
 	for i := range base {
 		currentPath := path + fmt.Sprintf("[%d]", i)
 
-		childValue, err := at.childTransformer.transform(in, pathReplace(oldPath, currentPath, modifier))
+		childValue, err := at.childTransformer.transform(call, in, pathReplace(oldPath, currentPath, modifier))
+		if err == errNullValue {
+			continue
+		}
 		if err != nil {
 			return nil, err
 		}
@@ -207,7 +231,7 @@ func (at *arrayTransformer) arrayTransformJSON(in interface{}, modifier pathModi
 		}
 	}
 
-	if len(newArray) == 0 {
+	if len(newArray) == 0 && (base == nil || !at.emptyValues.keepEmptyArray(call)) {
 		return nil, nil
 	}
 	return newArray, nil
@@ -215,12 +239,12 @@ func (at *arrayTransformer) arrayTransformJSON(in interface{}, modifier pathModi
 
 // arrayTransformXML retrieves the value for this object by building the value for the base object and then adding in any
 // transforms for all defined child fields.
-func (at *arrayTransformer) arrayTransformXML(in interface{}, modifier pathModifier) (interface{}, error) {
+func (at *arrayTransformer) arrayTransformXML(call *transformCall, in interface{}, modifier pathModifier) (interface{}, error) {
 	path := at.jsonPath
 	if modifier != nil {
 		path = modifier(path)
 	}
-	base, _, err := at.baseValue(in, path, modifier)
+	base, _, err := at.baseValue(call, in, path, modifier)
 	if err != nil {
 		return nil, err
 	}
@@ -236,7 +260,10 @@ func (at *arrayTransformer) arrayTransformXML(in interface{}, modifier pathModif
 		currentPath := path + fmt.Sprintf("[%d]", i)
 		childValue := base[i]
 		if _, ok := childValue.(*xmlquery.Node); ok {
-			childValue, err = at.childTransformer.transform(childValue, pathReplace(oldPath, currentPath, modifier))
+			childValue, err = at.childTransformer.transform(call, childValue, pathReplace(oldPath, currentPath, modifier))
+			if err == errNullValue {
+				continue
+			}
 			if err != nil {
 				return nil, err
 			}
@@ -246,19 +273,19 @@ func (at *arrayTransformer) arrayTransformXML(in interface{}, modifier pathModif
 		}
 	}
 
-	if len(newArray) == 0 {
+	if len(newArray) == 0 && (base == nil || !at.emptyValues.keepEmptyArray(call)) {
 		return nil, nil
 	}
 	return newArray, nil
 }
 
 // transform routes to the correct array transform type.
-func (at *arrayTransformer) transform(in interface{}, modifier pathModifier) (interface{}, error) {
+func (at *arrayTransformer) transform(call *transformCall, in interface{}, modifier pathModifier) (interface{}, error) {
 	if at.format == jsonInput {
-		return at.arrayTransformJSON(in, modifier)
+		return at.arrayTransformJSON(call, in, modifier)
 	}
 	if at.format == xmlInput {
-		return at.arrayTransformXML(in, modifier)
+		return at.arrayTransformXML(call, in, modifier)
 	}
 	return nil, fmt.Errorf("Unrecognized transform type %s in arraytransformer transform, must be 'JSON' or 'XML' ", at.format)
 }
@@ -266,21 +293,24 @@ func (at *arrayTransformer) transform(in interface{}, modifier pathModifier) (in
 // objectTransformer represents a JSON instance of type object and associated transforms.
 type objectTransformer struct {
 	children     map[string]instanceTransformer
+	required     []string
+	emptyValues  map[string]*fieldEmptyValues // The empty value settings of the children by name
 	defaultValue map[string]interface{}
 	jsonPath     string
 	format       inputFormat
 	transforms   *transformInstructions
 }
 
-func newObjectTransformer(path, transformIdentifier string, raw json.RawMessage, format inputFormat) (*objectTransformer, error) {
+func newObjectTransformer(path string, transformIdentifiers []string, raw json.RawMessage, format inputFormat) (*objectTransformer, error) {
 	ot := &objectTransformer{
-		children: make(map[string]instanceTransformer),
-		jsonPath: path,
-		format:   format,
+		children:    make(map[string]instanceTransformer),
+		emptyValues: make(map[string]*fieldEmptyValues),
+		jsonPath:    path,
+		format:      format,
 	}
 
 	var err error
-	ot.transforms, err = extractTransformInstructions(raw, transformIdentifier, path, "object")
+	ot.transforms, err = extractTransformInstructions(raw, transformIdentifiers, path, "object")
 	if err != nil {
 		return nil, err
 	}
@@ -297,6 +327,14 @@ func newObjectTransformer(path, transformIdentifier string, raw json.RawMessage,
 		}
 	}
 
+	if _, err := jsonparser.ArrayEach(raw, func(value []byte, dataType jsonparser.ValueType, _ int, _ error) {
+		if dataType == jsonparser.String {
+			ot.required = append(ot.required, string(value))
+		}
+	}, "required"); err != nil && err != jsonparser.KeyPathNotFoundError {
+		return nil, fmt.Errorf("failed to extract required fields: %v", err)
+	}
+
 	return ot, nil
 }
 
@@ -318,7 +356,7 @@ func (ot *objectTransformer) selectChild(key string) instanceTransformer { retur
 
 // objectTransformJSON retrieves the value for this object by building the value for the base object and then adding in any
 // transforms for all defined child fields.
-func (ot *objectTransformer) objectTransformJSON(in interface{}, modifier pathModifier) (interface{}, error) {
+func (ot *objectTransformer) objectTransformJSON(call *transformCall, in interface{}, modifier pathModifier) (interface{}, error) {
 	path := ot.jsonPath
 	if modifier != nil {
 		path = modifier(path)
@@ -327,7 +365,7 @@ func (ot *objectTransformer) objectTransformJSON(in interface{}, modifier pathMo
 
 	// For the object use a transform if it exists or the default or an empty map
 	if ot.transforms != nil {
-		rawValue, err := ot.transforms.transform(in, "object", modifier, ot.format)
+		rawValue, err := ot.transforms.transform(call, in, "object", modifier, ot.format)
 		if err != nil {
 			return nil, err
 		}
@@ -349,18 +387,31 @@ func (ot *objectTransformer) objectTransformJSON(in interface{}, modifier pathMo
 
 	// Add each child value to the paren
 	for _, child := range ot.children {
-		childValue, err := child.transform(in, modifier)
-		if err != nil {
+		childValue, err := child.transform(call, in, modifier)
+		if err != nil && err != errNullValue {
 			return nil, err
 		}
 
 		savePath := strings.Replace(child.path(), ot.jsonPath, "$", 1)
+		name := strings.TrimPrefix(savePath, "$.")
+		if err == errNullValue {
+			ot.emptyValues[name].saveNull(newValue, name)
+			continue
+		}
+		childValue, null := ot.emptyValues[name].apply(call, childValue)
+		if null {
+			newValue[name] = nil
+			continue
+		}
 		if err := saveInTree(newValue, savePath, childValue); err != nil {
 			return nil, fmt.Errorf("path %q failed save: %v", child.path(), err)
 		}
 	}
 
-	if len(newValue) == 0 {
+	if err := ot.checkRequired(call, newValue, modifier); err != nil {
+		return nil, err
+	}
+	if !hasValue(newValue) {
 		return nil, nil
 	}
 
@@ -370,7 +421,7 @@ func (ot *objectTransformer) objectTransformJSON(in interface{}, modifier pathMo
 // objectTransformXML retrieves the value for this object by building the value for the base object and then adding in any
 // transforms for all defined child fields. If a transform is provided it transforms the children relative to the
 // passed in node. If a transform is provided and not found the children of the object are skipped.
-func (ot *objectTransformer) objectTransformXML(in interface{}, modifier pathModifier) (interface{}, error) {
+func (ot *objectTransformer) objectTransformXML(call *transformCall, in interface{}, modifier pathModifier) (interface{}, error) {
 	path := ot.jsonPath
 	if modifier != nil {
 		path = modifier(path)
@@ -379,7 +430,7 @@ func (ot *objectTransformer) objectTransformXML(in interface{}, modifier pathMod
 	// For the object use a transform if it exists, if the transform does not find a node it will return nil unless a
 	// default value is specified in which case the default value will be returned
 	if ot.transforms != nil {
-		rawValue, err := ot.transforms.transform(in, "object", modifier, ot.format)
+		rawValue, err := ot.transforms.transform(call, in, "object", modifier, ot.format)
 		if err != nil {
 			return nil, err
 		}
@@ -419,18 +470,31 @@ func (ot *objectTransformer) objectTransformXML(in interface{}, modifier pathMod
 
 	// Add each child value to the parent if there is no object transform or if the object transform node is found
 	for _, child := range ot.children {
-		childValue, err := child.transform(in, modifier)
-		if err != nil {
+		childValue, err := child.transform(call, in, modifier)
+		if err != nil && err != errNullValue {
 			return nil, err
 		}
 
 		savePath := strings.Replace(child.path(), ot.jsonPath, "$", 1)
+		name := strings.TrimPrefix(savePath, "$.")
+		if err == errNullValue {
+			ot.emptyValues[name].saveNull(newValue, name)
+			continue
+		}
+		childValue, null := ot.emptyValues[name].apply(call, childValue)
+		if null {
+			newValue[name] = nil
+			continue
+		}
 		if err := saveInTree(newValue, savePath, childValue); err != nil {
 			return nil, fmt.Errorf("path %q failed save: %v", child.path(), err)
 		}
 	}
 
-	if len(newValue) == 0 {
+	if err := ot.checkRequired(call, newValue, modifier); err != nil {
+		return nil, err
+	}
+	if !hasValue(newValue) {
 		return nil, nil
 	}
 
@@ -438,12 +502,12 @@ func (ot *objectTransformer) objectTransformXML(in interface{}, modifier pathMod
 }
 
 // transform routes to the correct object transform type.
-func (ot *objectTransformer) transform(in interface{}, modifier pathModifier) (interface{}, error) {
+func (ot *objectTransformer) transform(call *transformCall, in interface{}, modifier pathModifier) (interface{}, error) {
 	if ot.format == jsonInput {
-		return ot.objectTransformJSON(in, modifier)
+		return ot.objectTransformJSON(call, in, modifier)
 	}
 	if ot.format == xmlInput {
-		return ot.objectTransformXML(in, modifier)
+		return ot.objectTransformXML(call, in, modifier)
 	}
 	return nil, fmt.Errorf("Unrecognized transform type %s in objecttransformer transform, must be 'JSON' or 'XML' ", ot.format)
 }
@@ -455,9 +519,10 @@ type scalarTransformer struct {
 	jsonPath     string
 	format       inputFormat
 	transforms   *transformInstructions
+	xmlFallback  *transformInstruction // Selects the element at the same path as the field
 }
 
-func newScalarTransformer(path, transformIdentifier string, raw json.RawMessage, instanceType string, format inputFormat) (*scalarTransformer, error) {
+func newScalarTransformer(path string, transformIdentifiers []string, raw json.RawMessage, instanceType string, format inputFormat) (*scalarTransformer, error) {
 	st := &scalarTransformer{
 		jsonType: instanceType,
 		jsonPath: path,
@@ -475,7 +540,7 @@ func newScalarTransformer(path, transformIdentifier string, raw json.RawMessage,
 	}
 
 	var err error
-	st.transforms, err = extractTransformInstructions(raw, transformIdentifier, path, "scalar")
+	st.transforms, err = extractTransformInstructions(raw, transformIdentifiers, path, "scalar")
 	if err != nil {
 		return nil, err
 	}
@@ -500,14 +565,14 @@ func (st *scalarTransformer) selectChild(string) instanceTransformer { return ni
 // 2. Look for the same jsonPath in the input and use directly if possible.
 //
 // 3. Fall back to the JSON Schema default value.
-func (st *scalarTransformer) transformScalarJSON(in interface{}, modifier pathModifier) (interface{}, error) {
+func (st *scalarTransformer) transformScalarJSON(call *transformCall, in interface{}, modifier pathModifier) (interface{}, error) {
 	path := st.jsonPath
 	if modifier != nil {
 		path = modifier(path)
 	}
 	// 1. Use a transform if it exists
 	if st.transforms != nil {
-		newValue, err := st.transforms.transform(in, st.jsonType, modifier, st.format)
+		newValue, err := st.transforms.transform(call, in, st.jsonType, modifier, st.format)
 		if err != nil {
 			return nil, err
 		}
@@ -534,8 +599,10 @@ func (st *scalarTransformer) transformScalarJSON(in interface{}, modifier pathMo
 //
 // 1. Use a Transform if it exists.
 //
-// 2. If transform does not exist or returns no value send back default.
-func (st *scalarTransformer) transformScalarXML(in interface{}, modifier pathModifier) (interface{}, error) {
+// 2. Look for the element at the same path relative to the current node and use it if possible.
+//
+// 3. Fall back to the JSON Schema default value.
+func (st *scalarTransformer) transformScalarXML(call *transformCall, in interface{}, modifier pathModifier) (interface{}, error) {
 	path := st.jsonPath
 	if modifier != nil {
 		path = modifier(path)
@@ -543,7 +610,7 @@ func (st *scalarTransformer) transformScalarXML(in interface{}, modifier pathMod
 
 	// 1. Use a Transform if it exists.
 	if st.transforms != nil {
-		newValue, err := st.transforms.transform(in, st.jsonType, modifier, st.format)
+		newValue, err := st.transforms.transform(call, in, st.jsonType, modifier, st.format)
 		if err != nil {
 			return nil, err
 		}
@@ -552,17 +619,27 @@ func (st *scalarTransformer) transformScalarXML(in interface{}, modifier pathMod
 		}
 	}
 
-	// 2. If transform does not exist or returns no value send back default
+	// 2. Look for the element at the same path relative to the current node.
+	if st.xmlFallback != nil {
+		newValue, err := st.xmlFallback.transform(call, in, st.jsonType, nil, st.format)
+		// if there is a conversion error or the nodes could not be converted fall through to the default
+		_, unconverted := newValue.([]*xmlquery.Node)
+		if err == nil && newValue != nil && !unconverted {
+			return newValue, nil
+		}
+	}
+
+	// 3. Fall back to the JSON Schema default value.
 	return st.defaultValue, nil
 }
 
 // transform routes to the correct scalar transform type.
-func (st *scalarTransformer) transform(in interface{}, modifier pathModifier) (interface{}, error) {
+func (st *scalarTransformer) transform(call *transformCall, in interface{}, modifier pathModifier) (interface{}, error) {
 	if st.format == jsonInput {
-		return st.transformScalarJSON(in, modifier)
+		return st.transformScalarJSON(call, in, modifier)
 	}
 	if st.format == xmlInput {
-		return st.transformScalarXML(in, modifier)
+		return st.transformScalarXML(call, in, modifier)
 	}
 	return nil, fmt.Errorf("Unrecognized transform type %s in scalartransformer transform, must be 'JSON' or 'XML' ", st.format)
 }
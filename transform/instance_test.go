@@ -290,7 +290,7 @@ func TestArrayTransform(t *testing.T) {
 	}
 
 	for _, test := range tests {
-		at, err := newArrayTransformer(test.path, "test", test.raw, test.format)
+		at, err := newArrayTransformer(test.path, []string{"test"}, test.raw, test.format)
 		if err != nil {
 			t.Fatalf("Test %q - failed to initialize array transformer: %v", test.description, err)
 		}
@@ -301,7 +301,7 @@ func TestArrayTransform(t *testing.T) {
 		for k, v := range testIn {
 			testInCopy[k] = v
 		}
-		got, err := at.transform(testInCopy, nil)
+		got, err := at.transform(nil, testInCopy, nil)
 		if err != nil {
 			t.Errorf("Test %q - failed transform: %v", test.description, err)
 		}
@@ -417,14 +417,14 @@ func TestObjectTransform(t *testing.T) {
 	}
 
 	for _, test := range tests {
-		ot, err := newObjectTransformer(test.path, "test", test.raw, test.format)
+		ot, err := newObjectTransformer(test.path, []string{"test"}, test.raw, test.format)
 		if err != nil {
 			t.Fatalf("Test %q - failed to initialize object transformer: %v", test.description, err)
 		}
 
 		ot.children = test.children
 
-		got, err := ot.transform(test.in, nil)
+		got, err := ot.transform(nil, test.in, nil)
 		if err != nil {
 			t.Errorf("Test %q - failed transform: %v", test.description, err)
 		}
@@ -559,6 +559,15 @@ func TestScalarTransform(t *testing.T) {
 			raw:          json.RawMessage(`{ "type": "boolean","transform":{"test":{"from":[{"jsonPath":"$.published"}]}}}`),
 			want:         true,
 		},
+		{
+			description:  "string transform truncated to the maxLength",
+			in:           testIn,
+			path:         "$.type",
+			instanceType: "string",
+			format:       jsonInput,
+			raw:          json.RawMessage(`{"type":"string","maxLength":7,"transform":{"test":{"from":[{"jsonPath":"$.publishUrl","operations":[{"type":"truncate","args":{"ellipsis":"","wordBoundary":"false"}}]}]}}}`),
+			want:         "publish",
+		},
 		{
 			description:  "time transform with bad formatting",
 			in:           testInBadTime,
@@ -571,12 +580,12 @@ func TestScalarTransform(t *testing.T) {
 	}
 
 	for _, test := range tests {
-		st, err := newScalarTransformer(test.path, "test", test.raw, test.instanceType, test.format)
+		st, err := newScalarTransformer(test.path, []string{"test"}, test.raw, test.instanceType, test.format)
 		if err != nil {
 			t.Fatalf("Test %q - failed to initialize scalar transformer: %v", test.description, err)
 		}
 
-		got, err := st.transform(test.in, nil)
+		got, err := st.transform(nil, test.in, nil)
 
 		if err != nil {
 			if err.Error() == test.wantError {
@@ -0,0 +1,139 @@
+package transform
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+
+	"github.com/GannettDigital/jsonparser"
+)
+
+// Mapping holds transform sections kept in a file separate from the schema, keyed by the jsonPath of the field in the
+// schema, ie `$.headline` or `$.images[*].url`. This allows a consumer to maintain its transforms without changing
+// the schema.
+//
+// Each section is in the same format as a transform section of the schema with an optional `mode`. With the default
+// mode `override` the section replaces any transform section of the field in the schema. With the mode `merge` the
+// section is combined with the inline transform section, the instructions of both are used with those of the mapping
+// tried first while the other keys of the mapping replace those of the inline section.
+type Mapping struct {
+	sections map[string]mappingSection
+}
+
+// mappingSection is the transform section for a single field of a Mapping.
+type mappingSection struct {
+	merge bool
+	raw   json.RawMessage
+}
+
+// MappingFromFile reads a Mapping from a JSON file.
+func MappingFromFile(mappingPath string) (*Mapping, error) {
+	data, err := os.ReadFile(mappingPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read mapping file %q: %v", mappingPath, err)
+	}
+	mapping, err := NewMapping(data)
+	if err != nil {
+		return nil, fmt.Errorf("invalid mapping file %q: %v", mappingPath, err)
+	}
+	return mapping, nil
+}
+
+// NewMapping parses a Mapping from JSON.
+func NewMapping(data []byte) (*Mapping, error) {
+	var rawSections map[string]json.RawMessage
+	if err := json.Unmarshal(data, &rawSections); err != nil {
+		return nil, fmt.Errorf("failed to parse mapping: %v", err)
+	}
+
+	mapping := &Mapping{sections: make(map[string]mappingSection, len(rawSections))}
+	for path, raw := range rawSections {
+		mode, err := jsonparser.GetString(raw, "mode")
+		if err != nil && err != jsonparser.KeyPathNotFoundError {
+			return nil, fmt.Errorf("failed to read the mode of %q: %v", path, err)
+		}
+		switch mode {
+		case "", "override", "merge":
+		default:
+			return nil, fmt.Errorf("unknown mode %q for %q, must be 'override' or 'merge'", mode, path)
+		}
+		mapping.sections[path] = mappingSection{merge: mode == "merge", raw: jsonparser.Delete(raw, "mode")}
+	}
+	return mapping, nil
+}
+
+// apply returns the schema of the field at path with the transform section of the mapping set for the identifier.
+// The transformIdentifiers are searched for the inline section merged with the mapping section.
+func (m *Mapping) apply(path string, raw json.RawMessage, transformIdentifiers []string) (json.RawMessage, error) {
+	section, ok := m.sections[path]
+	if !ok {
+		return raw, nil
+	}
+
+	transform := section.raw
+	if section.merge {
+		inline, err := findTransformSection(raw, transformIdentifiers)
+		if err != nil {
+			return nil, err
+		}
+		if transform, err = mergeTransformSections(inline, section.raw); err != nil {
+			return nil, fmt.Errorf("failed to merge the mapping for %q: %v", path, err)
+		}
+	}
+
+	// The raw schema is shared with other users of the schema so a copy is changed.
+	updated, err := jsonparser.Set(append(json.RawMessage{}, raw...), transform, "transform", transformIdentifiers[0])
+	if err != nil {
+		return nil, fmt.Errorf("failed to set the mapping for %q: %v", path, err)
+	}
+	return updated, nil
+}
+
+// unknownPaths returns the paths of the mapping which are not in the given set of schema paths.
+func (m *Mapping) unknownPaths(schemaPaths map[string]bool) []string {
+	var unknown []string
+	for path := range m.sections {
+		if !schemaPaths[path] {
+			unknown = append(unknown, path)
+		}
+	}
+	sort.Strings(unknown)
+	return unknown
+}
+
+// mergeTransformSections combines the inline transform section with that of a mapping. The `from` instructions of
+// the mapping are placed before the inline instructions, other keys of the mapping replace the inline ones.
+func mergeTransformSections(inline, mapping json.RawMessage) (json.RawMessage, error) {
+	if len(inline) == 0 {
+		return mapping, nil
+	}
+
+	var inlineKeys, mappingKeys map[string]json.RawMessage
+	if err := json.Unmarshal(inline, &inlineKeys); err != nil {
+		return nil, fmt.Errorf("failed to parse the inline transform: %v", err)
+	}
+	if err := json.Unmarshal(mapping, &mappingKeys); err != nil {
+		return nil, fmt.Errorf("failed to parse the mapping transform: %v", err)
+	}
+
+	for key, value := range mappingKeys {
+		if key != "from" || inlineKeys["from"] == nil {
+			inlineKeys[key] = value
+			continue
+		}
+		var mappingFrom, inlineFrom []json.RawMessage
+		if err := json.Unmarshal(value, &mappingFrom); err != nil {
+			return nil, fmt.Errorf("failed to parse the mapping instructions: %v", err)
+		}
+		if err := json.Unmarshal(inlineKeys["from"], &inlineFrom); err != nil {
+			return nil, fmt.Errorf("failed to parse the inline instructions: %v", err)
+		}
+		from, err := json.Marshal(append(mappingFrom, inlineFrom...))
+		if err != nil {
+			return nil, err
+		}
+		inlineKeys["from"] = from
+	}
+	return json.Marshal(inlineKeys)
+}
@@ -0,0 +1,116 @@
+package transform
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/GannettDigital/jsonparser"
+)
+
+func TestNewMapping(t *testing.T) {
+	tests := []struct {
+		description string
+		data        []byte
+		wantMerge   map[string]bool
+		wantErr     bool
+	}{
+		{
+			description: "default and explicit modes",
+			data:        []byte(`{"$.a": {"from": []}, "$.b": {"mode": "override", "from": []}, "$.c": {"mode": "merge", "from": []}}`),
+			wantMerge:   map[string]bool{"$.a": false, "$.b": false, "$.c": true},
+		},
+		{
+			description: "unknown mode",
+			data:        []byte(`{"$.a": {"mode": "replace", "from": []}}`),
+			wantErr:     true,
+		},
+		{
+			description: "invalid JSON",
+			data:        []byte(`["$.a"]`),
+			wantErr:     true,
+		},
+	}
+
+	for _, test := range tests {
+		mapping, err := NewMapping(test.data)
+		switch {
+		case test.wantErr && err != nil:
+			continue
+		case test.wantErr && err == nil:
+			t.Errorf("Test %q - got nil, want error", test.description)
+			continue
+		case !test.wantErr && err != nil:
+			t.Errorf("Test %q - got error, want nil: %v", test.description, err)
+			continue
+		}
+
+		if len(mapping.sections) != len(test.wantMerge) {
+			t.Errorf("Test %q - got %d sections, want %d", test.description, len(mapping.sections), len(test.wantMerge))
+		}
+		for path, merge := range test.wantMerge {
+			section, ok := mapping.sections[path]
+			if !ok {
+				t.Errorf("Test %q - no section for %q", test.description, path)
+				continue
+			}
+			if section.merge != merge {
+				t.Errorf("Test %q - got merge %t for %q, want %t", test.description, section.merge, path, merge)
+			}
+			if _, _, _, err := jsonparser.Get(section.raw, "mode"); err != jsonparser.KeyPathNotFoundError {
+				t.Errorf("Test %q - got section %s for %q, want the mode removed", test.description, section.raw, path)
+			}
+		}
+	}
+}
+
+func TestMergeTransformSections(t *testing.T) {
+	tests := []struct {
+		description string
+		inline      json.RawMessage
+		mapping     json.RawMessage
+		want        string
+		wantErr     bool
+	}{
+		{
+			description: "no inline section",
+			mapping:     json.RawMessage(`{"from":[{"jsonPath":"$.a"}]}`),
+			want:        `{"from":[{"jsonPath":"$.a"}]}`,
+		},
+		{
+			description: "mapping instructions first",
+			inline:      json.RawMessage(`{"from":[{"jsonPath":"$.b"}],"method":"first"}`),
+			mapping:     json.RawMessage(`{"from":[{"jsonPath":"$.a"}]}`),
+			want:        `{"from":[{"jsonPath":"$.a"},{"jsonPath":"$.b"}],"method":"first"}`,
+		},
+		{
+			description: "mapping keys replace inline keys",
+			inline:      json.RawMessage(`{"from":[{"jsonPath":"$.b"}],"method":"first"}`),
+			mapping:     json.RawMessage(`{"method":"concatenate","concatenateDelimiter":" "}`),
+			want:        `{"concatenateDelimiter":" ","from":[{"jsonPath":"$.b"}],"method":"concatenate"}`,
+		},
+		{
+			description: "invalid mapping instructions",
+			inline:      json.RawMessage(`{"from":[{"jsonPath":"$.b"}]}`),
+			mapping:     json.RawMessage(`{"from":{"jsonPath":"$.a"}}`),
+			wantErr:     true,
+		},
+	}
+
+	for _, test := range tests {
+		got, err := mergeTransformSections(test.inline, test.mapping)
+		switch {
+		case test.wantErr && err != nil:
+			continue
+		case test.wantErr && err == nil:
+			t.Errorf("Test %q - got nil, want error", test.description)
+			continue
+		case !test.wantErr && err != nil:
+			t.Errorf("Test %q - got error, want nil: %v", test.description, err)
+			continue
+		}
+
+		if string(got) != test.want {
+			t.Errorf("Test %q - got\n%s\nwant\n%s", test.description, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,149 @@
+package transform
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// onErrorPolicy is the `onError` setting of an operation which decides what happens when the operation fails.
+type onErrorPolicy string
+
+const (
+	onErrorFail    = onErrorPolicy("fail")    // Fail the transform, the default
+	onErrorSkip    = onErrorPolicy("skip")    // Pass the value to the next operation unchanged
+	onErrorNull    = onErrorPolicy("null")    // Write the field as null, or omit it when it is not nullable
+	onErrorDefault = onErrorPolicy("default") // Use the default from the field schema
+)
+
+// errNullValue is returned when an operation with the null onError policy fails. It stops the remaining operations
+// and fallbacks of the field, the object containing the field then writes it as null when it is nullable and omits it
+// otherwise.
+var errNullValue = errors.New("the value was nulled by the onError policy of an operation")
+
+// TransformMetadata describes the decisions made during a Transform call which are not visible in the output.
+type TransformMetadata struct {
+	OperationErrors []OperationError `json:"operationErrors,omitempty"`
+}
+
+// OperationError is an operation which failed and had its onError policy applied rather than failing the transform.
+type OperationError struct {
+	Field     string `json:"field"`     // The jsonPath of the field in the output
+	Path      string `json:"path"`      // The jsonPath or xmlPath of the value, empty for the operations on the combined value
+	Operation string `json:"operation"` // The type of the operation
+	Policy    string `json:"policy"`    // The onError policy applied, 'skip', 'null' or 'default'
+	Error     string `json:"error"`
+}
+
+// WithMetadata records the metadata of the call in the given TransformMetadata, ie the operations which failed and
+// had their onError policy applied. It is filled in even when the call returns an error.
+func WithMetadata(metadata *TransformMetadata) CallOption {
+	return func(call *transformCall) error {
+		if metadata == nil {
+			return errors.New("no TransformMetadata given to record the metadata in")
+		}
+		call.metadata = metadata
+		return nil
+	}
+}
+
+// onErrorOperation wraps a transformOperation which has an onError policy other than fail.
+type onErrorOperation struct {
+	transformOperation
+	name         string
+	policy       onErrorPolicy
+	defaultValue interface{}
+}
+
+// newOnErrorOperation returns the operation wrapped with the onError policy, operations which fail are returned as is.
+func newOnErrorOperation(op transformOperation, name, policy string) (transformOperation, error) {
+	switch onErrorPolicy(policy) {
+	case "", onErrorFail:
+		return op, nil
+	case onErrorSkip, onErrorNull, onErrorDefault:
+		return &onErrorOperation{transformOperation: op, name: name, policy: onErrorPolicy(policy)}, nil
+	}
+	return nil, fmt.Errorf("unknown onError %q for operation %q, must be 'fail', 'skip', 'null' or 'default'", policy, name)
+}
+
+// initSchema passes the schema to the wrapped operation and reads the default of the field for the default policy.
+func (o *onErrorOperation) initSchema(schema json.RawMessage) error {
+	if sop, ok := o.transformOperation.(schemaOperation); ok {
+		if err := sop.initSchema(schema); err != nil {
+			return err
+		}
+	}
+	if o.policy != onErrorDefault {
+		return nil
+	}
+
+	var err error
+	if o.defaultValue, err = schemaDefault(schema); err != nil {
+		return err
+	}
+	if o.defaultValue == nil {
+		return fmt.Errorf("the onError default of operation %q requires a default in the field schema", o.name)
+	}
+	return nil
+}
+
+func (o *onErrorOperation) transformWithCall(call *transformCall, in interface{}) (interface{}, error) {
+	return call.operate(o.transformOperation, in)
+}
+
+// runOperations runs the operations in order on the value applying the onError policy of those that fail. The path
+// is the jsonPath or xmlPath the value was read from. For the null policy errNullValue is returned, for the default
+// policy the field default is returned without running the remaining operations.
+func (call *transformCall) runOperations(operations []transformOperation, value interface{}, path string) (interface{}, error) {
+	for _, op := range operations {
+		result, err := call.operate(op, value)
+		if err == nil {
+			value = result
+			continue
+		}
+		oop, ok := op.(*onErrorOperation)
+		if !ok {
+			return nil, err
+		}
+
+		call.recordOperationError(OperationError{
+			Path:      path,
+			Operation: oop.name,
+			Policy:    string(oop.policy),
+			Error:     err.Error(),
+		})
+		switch oop.policy {
+		case onErrorNull:
+			return nil, errNullValue
+		case onErrorDefault:
+			return oop.defaultValue, nil
+		}
+	}
+	return value, nil
+}
+
+// recordOperationError adds the failed operation to the metadata of the call.
+func (call *transformCall) recordOperationError(oe OperationError) {
+	if call == nil {
+		return
+	}
+	call.metadata.OperationErrors = append(call.metadata.OperationErrors, oe)
+}
+
+// operationErrorCount returns the number of failed operations recorded so far.
+func (call *transformCall) operationErrorCount() int {
+	if call == nil {
+		return 0
+	}
+	return len(call.metadata.OperationErrors)
+}
+
+// setOperationErrorField sets the output field of the failed operations recorded after the first start.
+func (call *transformCall) setOperationErrorField(start int, field string) {
+	if call == nil {
+		return
+	}
+	for i := start; i < len(call.metadata.OperationErrors); i++ {
+		call.metadata.OperationErrors[i].Field = field
+	}
+}
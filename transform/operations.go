@@ -1,17 +1,34 @@
 package transform
 
 import (
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"html"
+	"io"
+	"math"
+	"net/url"
 	"regexp"
+	"slices"
 	"strconv"
 	"strings"
+	texttemplate "text/template"
 	"time"
+	"unicode"
+	"unicode/utf8"
 
 	jsonpath "github.com/GannettDigital/PaesslerAG_jsonpath"
+	"github.com/GannettDigital/jsonparser"
 	"github.com/antchfx/xmlquery"
+	"github.com/cespare/xxhash/v2"
 	"github.com/microcosm-cc/bluemonday"
+	xhtml "golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
 )
 
 var durationRe = regexp.MustCompile(`^([\d]*?):?([\d]*):([\d]*)$`)
@@ -169,13 +186,20 @@ func (m *max) transform(in interface{}) (interface{}, error) {
 		if err != nil {
 			return nil, fmt.Errorf("failed extracting 'by' field: %v", err)
 		}
-		by, ok := byRaw.(float64)
-		if !ok {
-			byInt, ok := byRaw.(int)
-			if !ok {
-				return nil, errors.New("by field is not a number")
+		var by float64
+		switch number := byRaw.(type) {
+		case float64:
+			by = number
+		case int:
+			by = float64(number)
+		case int64:
+			by = float64(number)
+		case json.Number:
+			if by, err = number.Float64(); err != nil {
+				return nil, fmt.Errorf("by field is not a valid number: %v", err)
 			}
-			by = float64(byInt)
+		default:
+			return nil, errors.New("by field is not a number")
 		}
 		if by > largest {
 			largest = by
@@ -202,16 +226,28 @@ func (r *replace) init(args map[string]string) error {
 	if err := requiredArgs([]string{"regex", "new"}, args); err != nil {
 		return err
 	}
-	re, err := regexp.Compile(args["regex"])
-	if err != nil {
-		return fmt.Errorf("failed to parse regex %q: %v", args["regex"], err)
+	if err := r.initStatic(args); err != nil {
+		return err
 	}
 
-	r.regex = re
 	r.args = args
 	return nil
 }
 
+// initStatic compiles the regex when it is given.
+func (r *replace) initStatic(args map[string]string) error {
+	regex, ok := args["regex"]
+	if !ok {
+		return nil
+	}
+	re, err := regexp.Compile(regex)
+	if err != nil {
+		return fmt.Errorf("failed to parse regex %q: %v", regex, err)
+	}
+	r.regex = re
+	return nil
+}
+
 func (r *replace) transform(raw interface{}) (interface{}, error) {
 	if r.regex == nil {
 		return nil, errors.New("init was not run")
@@ -288,7 +324,7 @@ func (t *timeParse) transform(raw interface{}) (interface{}, error) {
 }
 
 // currentTime is a transformOperation which returns the current time in a
-// specified format.
+// specified format. The time is from the Clock of the Transformer.
 type currentTime struct {
 	args map[string]string
 }
@@ -301,13 +337,17 @@ func (c *currentTime) init(args map[string]string) error {
 	return nil
 }
 
-func (c *currentTime) transform(_ interface{}) (interface{}, error) {
+func (c *currentTime) transform(in interface{}) (interface{}, error) {
+	return c.transformWithCall(nil, in)
+}
+
+func (c *currentTime) transformWithCall(call *transformCall, _ interface{}) (interface{}, error) {
 	timeFmt := c.args["format"]
 	switch c.args["format"] {
 	case "RFC3339":
 		timeFmt = time.RFC3339
 	}
-	return time.Now().Format(timeFmt), nil
+	return call.now().Format(timeFmt), nil
 }
 
 // toCamelCase is a transformOperation which converts strings with dashes to camelCase.
@@ -364,6 +404,476 @@ func (c *removeHTML) transform(raw interface{}) (interface{}, error) {
 	return html.UnescapeString(s), nil
 }
 
+// sanitizeHTML is a transformOperation which removes all html tags and attributes from a string except those allowed.
+// The 'tags' argument is a comma separated list of allowed tags and the 'attributes' argument a comma separated list of
+// allowed attributes, an attribute can be limited to a single tag with the form "tag:attribute". When neither argument
+// is given a policy suitable for user generated content is used.
+type sanitizeHTML struct {
+	args   map[string]string
+	policy *bluemonday.Policy
+}
+
+func (s *sanitizeHTML) init(args map[string]string) error {
+	if err := allowedArgs(nil, []string{"tags", "attributes"}, args); err != nil {
+		return err
+	}
+	s.args = args
+
+	if len(args) == 0 {
+		s.policy = bluemonday.UGCPolicy()
+		return nil
+	}
+
+	s.policy = bluemonday.NewPolicy().AddSpaceWhenStrippingTag(true)
+	s.policy.RequireParseableURLs(true)
+	s.policy.AllowRelativeURLs(true)
+	s.policy.AllowURLSchemes("http", "https", "mailto")
+	if tags := splitArg(args["tags"]); len(tags) > 0 {
+		s.policy.AllowElements(tags...)
+	}
+	for _, attribute := range splitArg(args["attributes"]) {
+		tag, attr, found := strings.Cut(attribute, ":")
+		if !found {
+			s.policy.AllowAttrs(attribute).Globally()
+			continue
+		}
+		if tag == "" || attr == "" {
+			return fmt.Errorf("invalid attribute %q, expected 'attribute' or 'tag:attribute'", attribute)
+		}
+		s.policy.AllowAttrs(attr).OnElements(tag)
+	}
+	return nil
+}
+
+func (s *sanitizeHTML) transform(raw interface{}) (interface{}, error) {
+	in, ok := raw.(string)
+	if !ok {
+		return nil, errors.New("sanitizeHTML only supports input of type string")
+	}
+	return strings.TrimSpace(s.policy.Sanitize(in)), nil
+}
+
+// htmlBlockElements are the elements which htmlToText separates from surrounding text with a blank line.
+var htmlBlockElements = map[atom.Atom]bool{
+	atom.Address: true, atom.Article: true, atom.Aside: true, atom.Blockquote: true, atom.Dd: true, atom.Div: true,
+	atom.Dl: true, atom.Dt: true, atom.Figcaption: true, atom.Figure: true, atom.Footer: true, atom.H1: true,
+	atom.H2: true, atom.H3: true, atom.H4: true, atom.H5: true, atom.H6: true, atom.Header: true, atom.Hr: true,
+	atom.Li: true, atom.Main: true, atom.Nav: true, atom.Ol: true, atom.P: true, atom.Pre: true, atom.Section: true,
+	atom.Table: true, atom.Tr: true, atom.Ul: true,
+}
+
+var blankLinesRe = regexp.MustCompile(`\n{3,}`)
+
+// htmlToText is a transformOperation which converts html to plain text. Unlike removeHTML paragraphs and other block
+// elements are kept as separate paragraphs and <br> tags as line breaks, html entities are decoded.
+type htmlToText struct {
+	args map[string]string
+}
+
+func (h *htmlToText) init(args map[string]string) error {
+	return nil
+}
+
+func (h *htmlToText) transform(raw interface{}) (interface{}, error) {
+	in, ok := raw.(string)
+	if !ok {
+		return nil, errors.New("htmlToText only supports input of type string")
+	}
+
+	var (
+		text  strings.Builder
+		skip  int // depth within elements whose content is not text, ie <script>
+		space bool
+	)
+	tokenizer := xhtml.NewTokenizer(strings.NewReader(in))
+	for {
+		tt := tokenizer.Next()
+		if tt == xhtml.ErrorToken {
+			if err := tokenizer.Err(); err != io.EOF {
+				return nil, fmt.Errorf("failed to parse html: %v", err)
+			}
+			break
+		}
+
+		token := tokenizer.Token()
+		switch tt {
+		case xhtml.StartTagToken, xhtml.EndTagToken, xhtml.SelfClosingTagToken:
+			switch {
+			case token.DataAtom == atom.Script || token.DataAtom == atom.Style:
+				if tt == xhtml.StartTagToken {
+					skip++
+				} else if tt == xhtml.EndTagToken && skip > 0 {
+					skip--
+				}
+			case token.DataAtom == atom.Br:
+				text.WriteString("\n")
+				space = false
+			case htmlBlockElements[token.DataAtom]:
+				text.WriteString("\n\n")
+				space = false
+			}
+		case xhtml.TextToken:
+			if skip > 0 {
+				continue
+			}
+			for _, r := range token.Data {
+				if unicode.IsSpace(r) && r != '\u00a0' {
+					space = true
+					continue
+				}
+				if space && text.Len() > 0 && !strings.HasSuffix(text.String(), "\n") {
+					text.WriteByte(' ')
+				}
+				space = false
+				text.WriteRune(r)
+			}
+		}
+	}
+
+	lines := strings.Split(text.String(), "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimSpace(line)
+	}
+	return strings.TrimSpace(blankLinesRe.ReplaceAllString(strings.Join(lines, "\n"), "\n\n")), nil
+}
+
+// htmlExtract is a transformOperation which returns an array with the value of the 'attribute' argument from every
+// html element named by the 'tag' argument, elements without the attribute are skipped.
+type htmlExtract struct {
+	args map[string]string
+}
+
+func (h *htmlExtract) init(args map[string]string) error {
+	if err := requiredArgs([]string{"tag", "attribute"}, args); err != nil {
+		return err
+	}
+	h.args = args
+	return nil
+}
+
+func (h *htmlExtract) transform(raw interface{}) (interface{}, error) {
+	in, ok := raw.(string)
+	if !ok {
+		return nil, errors.New("htmlExtract only supports input of type string")
+	}
+
+	values := []interface{}{}
+	tokenizer := xhtml.NewTokenizer(strings.NewReader(in))
+	for {
+		tt := tokenizer.Next()
+		if tt == xhtml.ErrorToken {
+			if err := tokenizer.Err(); err != io.EOF {
+				return nil, fmt.Errorf("failed to parse html: %v", err)
+			}
+			return values, nil
+		}
+		if tt != xhtml.StartTagToken && tt != xhtml.SelfClosingTagToken {
+			continue
+		}
+
+		token := tokenizer.Token()
+		if !strings.EqualFold(token.Data, h.args["tag"]) {
+			continue
+		}
+		for _, attr := range token.Attr {
+			if strings.EqualFold(attr.Key, h.args["attribute"]) {
+				values = append(values, attr.Val)
+				break
+			}
+		}
+	}
+}
+
+// hashAlgorithms maps the names allowed for the hash 'algorithm' argument to a function returning the checksum.
+var hashAlgorithms = map[string]func([]byte) []byte{
+	"sha1": func(data []byte) []byte {
+		sum := sha1.Sum(data)
+		return sum[:]
+	},
+	"sha256": func(data []byte) []byte {
+		sum := sha256.Sum256(data)
+		return sum[:]
+	},
+	"xxhash": func(data []byte) []byte {
+		return binary.BigEndian.AppendUint64(nil, xxhash.Sum64(data))
+	},
+}
+
+// hash is a transformOperation which returns a checksum of the string form of a value. The 'algorithm' argument is
+// one of sha1, sha256 or xxhash and the optional 'encoding' argument selects hex, the default, or base64 output.
+type hash struct {
+	args     map[string]string
+	checksum func([]byte) []byte
+}
+
+func (h *hash) init(args map[string]string) error {
+	if err := allowedArgs([]string{"algorithm"}, []string{"encoding"}, args); err != nil {
+		return err
+	}
+	checksum, ok := hashAlgorithms[args["algorithm"]]
+	if !ok {
+		return fmt.Errorf("the argument 'algorithm' must be one of 'sha1', 'sha256' or 'xxhash', got %q", args["algorithm"])
+	}
+	if encoding, ok := args["encoding"]; ok && encoding != "hex" && encoding != "base64" {
+		return fmt.Errorf("the argument 'encoding' must be either 'hex' or 'base64', got %q", encoding)
+	}
+
+	h.checksum = checksum
+	h.args = args
+	return nil
+}
+
+func (h *hash) transform(raw interface{}) (interface{}, error) {
+	in, err := convertString(raw)
+	if err != nil || in == nil {
+		return nil, errors.New("hash only supports strings, numbers and booleans")
+	}
+
+	sum := h.checksum([]byte(in.(string)))
+	if h.args["encoding"] == "base64" {
+		return base64.StdEncoding.EncodeToString(sum), nil
+	}
+	return hex.EncodeToString(sum), nil
+}
+
+// uuidNamespaces are the predefined name space UUIDs from RFC 4122 which can be referenced by name in the uuidv5
+// 'namespace' argument.
+var uuidNamespaces = map[string]string{
+	"dns":  "6ba7b810-9dad-11d1-80b4-00c04fd430c8",
+	"url":  "6ba7b811-9dad-11d1-80b4-00c04fd430c8",
+	"oid":  "6ba7b812-9dad-11d1-80b4-00c04fd430c8",
+	"x500": "6ba7b814-9dad-11d1-80b4-00c04fd430c8",
+}
+
+// uuidv5 is a transformOperation which returns the name based, SHA-1, version 5 UUID of the string form of a value.
+// The 'namespace' argument is either a UUID or one of the predefined namespaces dns, url, oid or x500.
+type uuidv5 struct {
+	args      map[string]string
+	namespace []byte
+}
+
+func (u *uuidv5) init(args map[string]string) error {
+	if err := requiredArgs([]string{"namespace"}, args); err != nil {
+		return err
+	}
+	namespace := args["namespace"]
+	if predefined, ok := uuidNamespaces[namespace]; ok {
+		namespace = predefined
+	}
+	parsed, err := parseUUID(namespace)
+	if err != nil {
+		return fmt.Errorf("the argument 'namespace' must be a UUID or one of dns, url, oid or x500: %v", err)
+	}
+
+	u.namespace = parsed
+	u.args = args
+	return nil
+}
+
+func (u *uuidv5) transform(raw interface{}) (interface{}, error) {
+	in, err := convertString(raw)
+	if err != nil || in == nil {
+		return nil, errors.New("uuidv5 only supports strings, numbers and booleans")
+	}
+
+	h := sha1.New()
+	h.Write(u.namespace)
+	h.Write([]byte(in.(string)))
+	uuid := h.Sum(nil)[:16]
+	uuid[6] = (uuid[6] & 0x0f) | 0x50 // version 5
+	uuid[8] = (uuid[8] & 0x3f) | 0x80 // RFC 4122 variant
+
+	return fmt.Sprintf("%x-%x-%x-%x-%x", uuid[0:4], uuid[4:6], uuid[6:8], uuid[8:10], uuid[10:16]), nil
+}
+
+// parseUUID parses a UUID in the canonical 8-4-4-4-12 hex format returning the 16 bytes.
+func parseUUID(uuid string) ([]byte, error) {
+	if len(uuid) != 36 || uuid[8] != '-' || uuid[13] != '-' || uuid[18] != '-' || uuid[23] != '-' {
+		return nil, fmt.Errorf("invalid UUID format %q", uuid)
+	}
+	parsed, err := hex.DecodeString(strings.ReplaceAll(uuid, "-", ""))
+	if err != nil {
+		return nil, fmt.Errorf("invalid UUID %q: %v", uuid, err)
+	}
+	return parsed, nil
+}
+
+// resolve is a transformOperation which looks up the value in one of the Resolvers registered with the Transformer.
+// The 'resolver' argument is the name of the Resolver, the optional 'return' argument is a relative JSONPath selector,
+// ie `@.name`, which identifies the part of the resolved value to return. Arrays are resolved item by item.
+type resolve struct {
+	args map[string]string
+}
+
+func (r *resolve) init(args map[string]string) error {
+	if err := allowedArgs([]string{"resolver"}, []string{"return"}, args); err != nil {
+		return err
+	}
+	if ret, ok := args["return"]; ok && !strings.HasPrefix(ret, "@") {
+		return fmt.Errorf("the argument 'return' must be a relative JSONPath starting with '@', got %q", ret)
+	}
+
+	r.args = args
+	return nil
+}
+
+func (r *resolve) transform(raw interface{}) (interface{}, error) {
+	return r.transformWithCall(nil, raw)
+}
+
+func (r *resolve) transformWithCall(call *transformCall, raw interface{}) (interface{}, error) {
+	if items, ok := raw.([]interface{}); ok {
+		var resolved []interface{}
+		for _, item := range items {
+			value, err := r.resolveKey(call, item)
+			if err != nil {
+				return nil, err
+			}
+			if value != nil {
+				resolved = append(resolved, value)
+			}
+		}
+		if len(resolved) == 0 {
+			return nil, nil
+		}
+		return resolved, nil
+	}
+
+	return r.resolveKey(call, raw)
+}
+
+func (r *resolve) resolveKey(call *transformCall, raw interface{}) (interface{}, error) {
+	key, err := convertString(raw)
+	if err != nil || key == nil {
+		return nil, errors.New("resolve only supports strings, numbers and booleans as keys")
+	}
+
+	value, err := call.resolve(r.args["resolver"], key.(string))
+	if err != nil {
+		return nil, err
+	}
+	ret, ok := r.args["return"]
+	if !ok || value == nil {
+		return value, nil
+	}
+
+	returnValue, err := jsonpath.Get(strings.Replace(ret, "@", "$", 1), value)
+	if err != nil {
+		return nil, nil
+	}
+	return returnValue, nil
+}
+
+// join is a transformOperation which combines the items of an array with the items of an array from another named
+// input, see WithInputs, which have the same key. By default the keys of the matching item are added to the item, with
+// the 'as' argument the matching item is set under that key instead. Items without a match are kept unless the 'type'
+// is inner. It fails without named inputs or when the 'with' array is not found.
+type join struct {
+	args map[string]string
+}
+
+func (j *join) init(args map[string]string) error {
+	if err := allowedArgs([]string{"with", "on"}, []string{"withOn", "as", "type"}, args); err != nil {
+		return err
+	}
+	if !strings.HasPrefix(args["with"], inputsPrefix) {
+		return fmt.Errorf("the argument 'with' must be a jsonPath starting with %q, got %q", inputsPrefix, args["with"])
+	}
+	for _, name := range []string{"on", "withOn"} {
+		if arg, ok := args[name]; ok && !strings.HasPrefix(arg, "@") {
+			return fmt.Errorf("the argument %q must be a relative JSONPath starting with '@', got %q", name, arg)
+		}
+	}
+	switch args["type"] {
+	case "", "left", "inner":
+	default:
+		return fmt.Errorf("unknown join type %q, must be 'left' or 'inner'", args["type"])
+	}
+
+	j.args = args
+	return nil
+}
+
+func (j *join) transform(raw interface{}) (interface{}, error) {
+	return j.transformWithCall(nil, raw)
+}
+
+func (j *join) transformWithCall(call *transformCall, raw interface{}) (interface{}, error) {
+	items, ok := raw.([]interface{})
+	if !ok {
+		return nil, errors.New("join only supports arrays")
+	}
+
+	withOn, ok := j.args["withOn"]
+	if !ok {
+		withOn = j.args["on"]
+	}
+	if call == nil || call.inputs == nil {
+		return nil, errors.New("join requires named inputs, see WithInputs")
+	}
+	with, ok := call.input("$" + strings.TrimPrefix(j.args["with"], "$inputs")).([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("no array found for the argument 'with' %q", j.args["with"])
+	}
+
+	matches := make(map[string]interface{})
+	for _, item := range with {
+		key := joinKey(item, withOn)
+		if _, ok := matches[key]; key != "" && !ok {
+			matches[key] = item
+		}
+	}
+
+	joined := make([]interface{}, 0, len(items))
+	for _, item := range items {
+		match, ok := matches[joinKey(item, j.args["on"])]
+		if !ok {
+			if j.args["type"] != "inner" {
+				joined = append(joined, item)
+			}
+			continue
+		}
+
+		itemMap, ok := item.(map[string]interface{})
+		if !ok {
+			return nil, errors.New("join only supports arrays of objects")
+		}
+		combined := make(map[string]interface{}, len(itemMap))
+		for key, value := range itemMap {
+			combined[key] = value
+		}
+		if as, ok := j.args["as"]; ok {
+			combined[as] = match
+			joined = append(joined, combined)
+			continue
+		}
+		matchMap, ok := match.(map[string]interface{})
+		if !ok {
+			return nil, errors.New("join only supports arrays of objects without the 'as' argument")
+		}
+		for key, value := range matchMap {
+			if _, ok := combined[key]; !ok {
+				combined[key] = value
+			}
+		}
+		joined = append(joined, combined)
+	}
+	return joined, nil
+}
+
+// joinKey returns the key of the item at the relative JSONPath or an empty string if it has none.
+func joinKey(item interface{}, path string) string {
+	value, err := jsonpath.Get(strings.Replace(path, "@", "$", 1), item)
+	if err != nil || value == nil {
+		return ""
+	}
+	key, err := convertString(value)
+	if err != nil || key == nil {
+		return ""
+	}
+	return key.(string)
+}
+
 // convertToFloat64 is a transformOperation which converts various types to float64.
 type convertToFloat64 struct {
 	args map[string]string
@@ -379,8 +889,12 @@ func (c *convertToFloat64) transform(raw interface{}) (interface{}, error) {
 		return strconv.ParseFloat(in, 64)
 	case int:
 		return float64(in), nil
+	case int64:
+		return float64(in), nil
 	case float64:
 		return in, nil
+	case json.Number:
+		return in.Float64()
 	default:
 		return nil, fmt.Errorf("convertToFloat64 only supports strings, int, and float64, raw type: %T", raw)
 	}
@@ -413,6 +927,15 @@ func (c *convertToInt64) transform(raw interface{}) (interface{}, error) {
 		return int64(in), nil
 	case int64:
 		return int64(in), nil
+	case json.Number:
+		if value, err := in.Int64(); err == nil {
+			return value, nil
+		}
+		value, err := in.Float64()
+		if err != nil {
+			return nil, fmt.Errorf("convertToInt64 failed for %s: %v", in, err)
+		}
+		return int64(value), nil
 	default:
 		return nil, fmt.Errorf("convertToInt64 only supports strings, int, and float64, raw type: %T", raw)
 	}
@@ -449,20 +972,719 @@ func (c *convertToBool) transform(raw interface{}) (interface{}, error) {
 		return in != 0, nil
 	case int64:
 		return in != 0, nil
+	case json.Number:
+		value, err := in.Float64()
+		return value != 0, err
 	default:
 		return false, fmt.Errorf("convertToBool only supports boolean, strings, ints, floats, and array types: %T", raw)
 	}
 }
 
-// requiredArgs checks the given args map to make sure it contains the required args
-// and only the required args.
-func requiredArgs(required []string, args map[string]string) error {
-	if len(args) != len(required) {
-		return fmt.Errorf("expected args %v but got %d args", required, len(args))
+// multiply is a transformOperation which multiplies a number by the 'by' argument.
+type multiply struct {
+	args map[string]string
+	by   float64
+}
+
+func (m *multiply) init(args map[string]string) error {
+	if err := requiredArgs([]string{"by"}, args); err != nil {
+		return err
 	}
-	for _, arg := range required {
-		if _, ok := args[arg]; !ok {
-			return fmt.Errorf("argument %q is required", arg)
+	by, err := strconv.ParseFloat(args["by"], 64)
+	if err != nil {
+		return fmt.Errorf("the argument 'by' must be a number: %v", err)
+	}
+
+	m.by = by
+	m.args = args
+	return nil
+}
+
+func (m *multiply) transform(raw interface{}) (interface{}, error) {
+	in, err := toFloat64(raw)
+	if err != nil {
+		return nil, fmt.Errorf("multiply %v", err)
+	}
+	return in * m.by, nil
+}
+
+// divide is a transformOperation which divides a number by the 'by' argument.
+type divide struct {
+	args map[string]string
+	by   float64
+}
+
+func (d *divide) init(args map[string]string) error {
+	if err := requiredArgs([]string{"by"}, args); err != nil {
+		return err
+	}
+	by, err := strconv.ParseFloat(args["by"], 64)
+	if err != nil {
+		return fmt.Errorf("the argument 'by' must be a number: %v", err)
+	}
+	if by == 0 {
+		return errors.New("the argument 'by' must not be zero")
+	}
+
+	d.by = by
+	d.args = args
+	return nil
+}
+
+func (d *divide) transform(raw interface{}) (interface{}, error) {
+	in, err := toFloat64(raw)
+	if err != nil {
+		return nil, fmt.Errorf("divide %v", err)
+	}
+	return in / d.by, nil
+}
+
+// add is a transformOperation which adds the 'value' argument to a number, negative values can be used to subtract.
+type add struct {
+	args  map[string]string
+	value float64
+}
+
+func (a *add) init(args map[string]string) error {
+	if err := requiredArgs([]string{"value"}, args); err != nil {
+		return err
+	}
+	value, err := strconv.ParseFloat(args["value"], 64)
+	if err != nil {
+		return fmt.Errorf("the argument 'value' must be a number: %v", err)
+	}
+
+	a.value = value
+	a.args = args
+	return nil
+}
+
+func (a *add) transform(raw interface{}) (interface{}, error) {
+	in, err := toFloat64(raw)
+	if err != nil {
+		return nil, fmt.Errorf("add %v", err)
+	}
+	return in + a.value, nil
+}
+
+// round is a transformOperation which rounds a number half away from zero to the number of decimal places given by
+// the optional 'precision' argument, the default precision is 0.
+type round struct {
+	args      map[string]string
+	precision int
+}
+
+func (r *round) init(args map[string]string) error {
+	if err := allowedArgs(nil, []string{"precision"}, args); err != nil {
+		return err
+	}
+	if rawPrecision, ok := args["precision"]; ok {
+		precision, err := strconv.Atoi(rawPrecision)
+		if err != nil || precision < 0 {
+			return fmt.Errorf("the argument 'precision' must be a non-negative integer, got %q", rawPrecision)
+		}
+		r.precision = precision
+	}
+
+	r.args = args
+	return nil
+}
+
+func (r *round) transform(raw interface{}) (interface{}, error) {
+	in, err := toFloat64(raw)
+	if err != nil {
+		return nil, fmt.Errorf("round %v", err)
+	}
+	shift := math.Pow(10, float64(r.precision))
+	return math.Round(in*shift) / shift, nil
+}
+
+// clamp is a transformOperation which limits a number to the range set by the 'min' and 'max' arguments, at least one
+// of the two is required.
+type clamp struct {
+	args map[string]string
+	min  float64
+	max  float64
+}
+
+func (c *clamp) init(args map[string]string) error {
+	if err := allowedArgs(nil, []string{"min", "max"}, args); err != nil {
+		return err
+	}
+	if len(args) == 0 {
+		return errors.New("at least one of the arguments 'min' or 'max' is required")
+	}
+
+	c.min = math.Inf(-1)
+	c.max = math.Inf(1)
+	var err error
+	if rawMin, ok := args["min"]; ok {
+		if c.min, err = strconv.ParseFloat(rawMin, 64); err != nil {
+			return fmt.Errorf("the argument 'min' must be a number: %v", err)
+		}
+	}
+	if rawMax, ok := args["max"]; ok {
+		if c.max, err = strconv.ParseFloat(rawMax, 64); err != nil {
+			return fmt.Errorf("the argument 'max' must be a number: %v", err)
+		}
+	}
+	if c.min > c.max {
+		return errors.New("the argument 'min' must not be larger than 'max'")
+	}
+
+	c.args = args
+	return nil
+}
+
+func (c *clamp) transform(raw interface{}) (interface{}, error) {
+	in, err := toFloat64(raw)
+	if err != nil {
+		return nil, fmt.Errorf("clamp %v", err)
+	}
+	return math.Min(math.Max(in, c.min), c.max), nil
+}
+
+// units maps the unit names supported by convertUnit to their dimension and their size in the base unit of that
+// dimension. The base units are bytes, seconds and bits per second.
+var units = map[string]struct {
+	dimension string
+	size      float64
+}{
+	"B":    {"data", 1},
+	"KB":   {"data", 1e3},
+	"MB":   {"data", 1e6},
+	"GB":   {"data", 1e9},
+	"TB":   {"data", 1e12},
+	"KiB":  {"data", 1 << 10},
+	"MiB":  {"data", 1 << 20},
+	"GiB":  {"data", 1 << 30},
+	"TiB":  {"data", 1 << 40},
+	"ns":   {"time", 1e-9},
+	"us":   {"time", 1e-6},
+	"ms":   {"time", 1e-3},
+	"s":    {"time", 1},
+	"min":  {"time", 60},
+	"h":    {"time", 3600},
+	"bps":  {"bitrate", 1},
+	"kbps": {"bitrate", 1e3},
+	"Mbps": {"bitrate", 1e6},
+	"Gbps": {"bitrate", 1e9},
+}
+
+// convertUnit is a transformOperation which converts a number between the data size, time or bitrate units named by
+// the 'from' and 'to' arguments.
+type convertUnit struct {
+	args   map[string]string
+	factor float64
+}
+
+func (c *convertUnit) init(args map[string]string) error {
+	if err := requiredArgs([]string{"from", "to"}, args); err != nil {
+		return err
+	}
+	from, ok := units[args["from"]]
+	if !ok {
+		return fmt.Errorf("unsupported unit %q for argument 'from'", args["from"])
+	}
+	to, ok := units[args["to"]]
+	if !ok {
+		return fmt.Errorf("unsupported unit %q for argument 'to'", args["to"])
+	}
+	if from.dimension != to.dimension {
+		return fmt.Errorf("unable to convert %s unit %q to %s unit %q", from.dimension, args["from"], to.dimension, args["to"])
+	}
+
+	c.factor = from.size / to.size
+	c.args = args
+	return nil
+}
+
+func (c *convertUnit) transform(raw interface{}) (interface{}, error) {
+	in, err := toFloat64(raw)
+	if err != nil {
+		return nil, fmt.Errorf("convertUnit %v", err)
+	}
+	return in * c.factor, nil
+}
+
+// trim is a transformOperation which removes leading and trailing whitespace from a string, or when the optional
+// 'chars' argument is given leading and trailing characters found in it.
+type trim struct {
+	args map[string]string
+}
+
+func (t *trim) init(args map[string]string) error {
+	if err := allowedArgs(nil, []string{"chars"}, args); err != nil {
+		return err
+	}
+	t.args = args
+	return nil
+}
+
+func (t *trim) transform(raw interface{}) (interface{}, error) {
+	in, ok := raw.(string)
+	if !ok {
+		return nil, errors.New("trim only supports strings")
+	}
+	if chars, ok := t.args["chars"]; ok {
+		return strings.Trim(in, chars), nil
+	}
+	return strings.TrimSpace(in), nil
+}
+
+// collapseWhitespace is a transformOperation which replaces each run of whitespace in a string with a single space and
+// trims the whitespace from both ends.
+type collapseWhitespace struct {
+	args map[string]string
+}
+
+func (c *collapseWhitespace) init(args map[string]string) error {
+	return nil
+}
+
+func (c *collapseWhitespace) transform(raw interface{}) (interface{}, error) {
+	in, ok := raw.(string)
+	if !ok {
+		return nil, errors.New("collapseWhitespace only supports strings")
+	}
+	return strings.Join(strings.Fields(in), " "), nil
+}
+
+// truncate is a transformOperation which shortens a string to at most 'length' characters including the ellipsis.
+// If no length is given the maxLength of the field in the schema is used. By default the string is cut at a word
+// boundary and "..." is appended, these are controlled by the 'wordBoundary' and 'ellipsis' arguments.
+type truncate struct {
+	args         map[string]string
+	ellipsis     string
+	length       int
+	wordBoundary bool
+}
+
+func (t *truncate) init(args map[string]string) error {
+	if err := allowedArgs(nil, []string{"length", "ellipsis", "wordBoundary"}, args); err != nil {
+		return err
+	}
+
+	t.ellipsis = "..."
+	if ellipsis, ok := args["ellipsis"]; ok {
+		t.ellipsis = ellipsis
+	}
+	t.wordBoundary = true
+	if rawWordBoundary, ok := args["wordBoundary"]; ok {
+		wordBoundary, err := strconv.ParseBool(rawWordBoundary)
+		if err != nil {
+			return fmt.Errorf("the argument 'wordBoundary' must be a boolean, got %q", rawWordBoundary)
+		}
+		t.wordBoundary = wordBoundary
+	}
+	if rawLength, ok := args["length"]; ok {
+		length, err := strconv.Atoi(rawLength)
+		if err != nil || length <= 0 {
+			return fmt.Errorf("the argument 'length' must be a positive integer, got %q", rawLength)
+		}
+		t.length = length
+	}
+
+	t.args = args
+	return nil
+}
+
+func (t *truncate) initSchema(schema json.RawMessage) error {
+	if t.length != 0 {
+		return nil
+	}
+	maxLength, err := jsonparser.GetInt(schema, "maxLength")
+	if err != nil {
+		return errors.New("truncate requires the argument 'length' when the field has no maxLength")
+	}
+	if maxLength <= 0 {
+		return fmt.Errorf("truncate requires a positive maxLength, got %d", maxLength)
+	}
+	t.length = int(maxLength)
+	return nil
+}
+
+func (t *truncate) transform(raw interface{}) (interface{}, error) {
+	if t.length == 0 {
+		return nil, errors.New("truncate has no length, the argument 'length' or a field maxLength is required")
+	}
+	in, ok := raw.(string)
+	if !ok {
+		return nil, errors.New("truncate only supports strings")
+	}
+
+	runes := []rune(in)
+	if len(runes) <= t.length {
+		return in, nil
+	}
+
+	limit := t.length - utf8.RuneCountInString(t.ellipsis)
+	if limit <= 0 {
+		return string(runes[:t.length]), nil
+	}
+
+	cut := runes[:limit]
+	if t.wordBoundary && !unicode.IsSpace(runes[limit]) {
+		for i := len(cut) - 1; i > 0; i-- {
+			if unicode.IsSpace(cut[i]) {
+				cut = cut[:i]
+				break
+			}
+		}
+	}
+
+	return strings.TrimRightFunc(string(cut), unicode.IsSpace) + t.ellipsis, nil
+}
+
+// prefix is a transformOperation which adds the 'value' argument to the beginning of a string.
+type prefix struct {
+	args map[string]string
+}
+
+func (p *prefix) init(args map[string]string) error {
+	if err := requiredArgs([]string{"value"}, args); err != nil {
+		return err
+	}
+	p.args = args
+	return nil
+}
+
+func (p *prefix) transform(raw interface{}) (interface{}, error) {
+	in, ok := raw.(string)
+	if !ok {
+		return nil, errors.New("prefix only supports strings")
+	}
+	return p.args["value"] + in, nil
+}
+
+// suffix is a transformOperation which adds the 'value' argument to the end of a string.
+type suffix struct {
+	args map[string]string
+}
+
+func (s *suffix) init(args map[string]string) error {
+	if err := requiredArgs([]string{"value"}, args); err != nil {
+		return err
+	}
+	s.args = args
+	return nil
+}
+
+func (s *suffix) transform(raw interface{}) (interface{}, error) {
+	in, ok := raw.(string)
+	if !ok {
+		return nil, errors.New("suffix only supports strings")
+	}
+	return in + s.args["value"], nil
+}
+
+// pad is a transformOperation which pads a string, or the text of a number, to the 'length' argument with the 'char'
+// argument, a space by default. The padding is added to the start unless the 'side' argument is "right".
+type pad struct {
+	length int
+	char   string
+	right  bool
+}
+
+func (p *pad) init(args map[string]string) error {
+	if err := allowedArgs([]string{"length"}, []string{"char", "side"}, args); err != nil {
+		return err
+	}
+	length, err := strconv.Atoi(args["length"])
+	if err != nil || length < 1 {
+		return fmt.Errorf("the argument 'length' must be a positive integer, got %q", args["length"])
+	}
+	p.length = length
+
+	p.char = " "
+	if char, ok := args["char"]; ok {
+		if utf8.RuneCountInString(char) != 1 {
+			return fmt.Errorf("the argument 'char' must be a single character, got %q", char)
+		}
+		p.char = char
+	}
+
+	switch args["side"] {
+	case "", "left":
+	case "right":
+		p.right = true
+	default:
+		return fmt.Errorf("the argument 'side' must be either 'left' or 'right', got %q", args["side"])
+	}
+	return nil
+}
+
+func (p *pad) transform(raw interface{}) (interface{}, error) {
+	in, err := convertString(raw)
+	if err != nil || in == nil {
+		return nil, errors.New("pad only supports strings, numbers and booleans")
+	}
+	text := in.(string)
+	missing := p.length - utf8.RuneCountInString(text)
+	if missing <= 0 {
+		return text, nil
+	}
+	if p.right {
+		return text + strings.Repeat(p.char, missing), nil
+	}
+	return strings.Repeat(p.char, missing) + text, nil
+}
+
+// template is a transformOperation which writes the value with the Go text/template in the 'template' argument, the
+// value is the dot of the template, ie `{{.}}` or `{{.name}}` for an object. Missing keys are an error.
+type template struct {
+	tmpl *texttemplate.Template
+}
+
+func (t *template) init(args map[string]string) error {
+	if err := requiredArgs([]string{"template"}, args); err != nil {
+		return err
+	}
+	tmpl, err := texttemplate.New("template").Option("missingkey=error").Parse(args["template"])
+	if err != nil {
+		return fmt.Errorf("failed to parse template: %v", err)
+	}
+	t.tmpl = tmpl
+	return nil
+}
+
+func (t *template) transform(raw interface{}) (interface{}, error) {
+	var out strings.Builder
+	if err := t.tmpl.Execute(&out, raw); err != nil {
+		return nil, fmt.Errorf("failed to execute template: %v", err)
+	}
+	return out.String(), nil
+}
+
+// urlEscapeMode checks the optional 'mode' argument of the urlEncode and urlDecode operations, it must be either
+// "query", the default, or "path".
+func urlEscapeMode(args map[string]string) error {
+	if err := allowedArgs(nil, []string{"mode"}, args); err != nil {
+		return err
+	}
+	if mode, ok := args["mode"]; ok && mode != "query" && mode != "path" {
+		return fmt.Errorf("the argument 'mode' must be either 'query' or 'path', got %q", mode)
+	}
+	return nil
+}
+
+// urlEncode is a transformOperation which escapes a string so it can be safely placed in a URL query, or with the
+// 'mode' argument set to "path" in a URL path segment.
+type urlEncode struct {
+	args map[string]string
+}
+
+func (u *urlEncode) init(args map[string]string) error {
+	if err := urlEscapeMode(args); err != nil {
+		return err
+	}
+	u.args = args
+	return nil
+}
+
+func (u *urlEncode) transform(raw interface{}) (interface{}, error) {
+	in, ok := raw.(string)
+	if !ok {
+		return nil, errors.New("urlEncode only supports strings")
+	}
+	if u.args["mode"] == "path" {
+		return url.PathEscape(in), nil
+	}
+	return url.QueryEscape(in), nil
+}
+
+// urlDecode is a transformOperation which reverses the escaping done by urlEncode, the 'mode' argument works the same.
+type urlDecode struct {
+	args map[string]string
+}
+
+func (u *urlDecode) init(args map[string]string) error {
+	if err := urlEscapeMode(args); err != nil {
+		return err
+	}
+	u.args = args
+	return nil
+}
+
+func (u *urlDecode) transform(raw interface{}) (interface{}, error) {
+	in, ok := raw.(string)
+	if !ok {
+		return nil, errors.New("urlDecode only supports strings")
+	}
+	unescape := url.QueryUnescape
+	if u.args["mode"] == "path" {
+		unescape = url.PathUnescape
+	}
+	decoded, err := unescape(in)
+	if err != nil {
+		return nil, fmt.Errorf("failed to URL decode: %v", err)
+	}
+	return decoded, nil
+}
+
+// base64Encodings maps the names allowed for the base64Decode 'encoding' argument to the encoding.
+var base64Encodings = map[string]*base64.Encoding{
+	"std":    base64.StdEncoding,
+	"url":    base64.URLEncoding,
+	"rawStd": base64.RawStdEncoding,
+	"rawURL": base64.RawURLEncoding,
+}
+
+// base64Decode is a transformOperation which decodes a base64 string, the optional 'encoding' argument selects the
+// alphabet and padding used, the default is "std".
+type base64Decode struct {
+	args     map[string]string
+	encoding *base64.Encoding
+}
+
+func (b *base64Decode) init(args map[string]string) error {
+	if err := allowedArgs(nil, []string{"encoding"}, args); err != nil {
+		return err
+	}
+	b.encoding = base64.StdEncoding
+	if name, ok := args["encoding"]; ok {
+		encoding, ok := base64Encodings[name]
+		if !ok {
+			return fmt.Errorf("the argument 'encoding' must be one of 'std', 'url', 'rawStd' or 'rawURL', got %q", name)
+		}
+		b.encoding = encoding
+	}
+
+	b.args = args
+	return nil
+}
+
+func (b *base64Decode) transform(raw interface{}) (interface{}, error) {
+	in, ok := raw.(string)
+	if !ok {
+		return nil, errors.New("base64Decode only supports strings")
+	}
+	decoded, err := b.encoding.DecodeString(in)
+	if err != nil {
+		return nil, fmt.Errorf("failed to base64 decode: %v", err)
+	}
+	return string(decoded), nil
+}
+
+// jsonParse is a transformOperation which parses JSON embedded in a string.
+type jsonParse struct {
+	args map[string]string
+}
+
+func (j *jsonParse) init(args map[string]string) error {
+	return nil
+}
+
+func (j *jsonParse) transform(raw interface{}) (interface{}, error) {
+	in, ok := raw.(string)
+	if !ok {
+		return nil, errors.New("jsonParse only supports strings")
+	}
+	parsed, err := decodeJSON([]byte(in))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse JSON: %v", err)
+	}
+	return parsed, nil
+}
+
+// urlParse is a transformOperation which extracts a single part of a URL. Either the 'part' argument naming the part
+// or the 'param' argument naming a query parameter must be given.
+type urlParse struct {
+	args map[string]string
+}
+
+func (u *urlParse) init(args map[string]string) error {
+	if err := allowedArgs(nil, []string{"part", "param"}, args); err != nil {
+		return err
+	}
+	if len(args) != 1 {
+		return errors.New("exactly one of the arguments 'part' or 'param' is required")
+	}
+	if part, ok := args["part"]; ok {
+		switch part {
+		case "scheme", "host", "hostname", "port", "path", "query", "fragment":
+		default:
+			return fmt.Errorf("the argument 'part' must be one of scheme, host, hostname, port, path, query or fragment, got %q", part)
+		}
+	}
+
+	u.args = args
+	return nil
+}
+
+func (u *urlParse) transform(raw interface{}) (interface{}, error) {
+	in, ok := raw.(string)
+	if !ok {
+		return nil, errors.New("urlParse only supports strings")
+	}
+	parsed, err := url.Parse(in)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse URL: %v", err)
+	}
+
+	if param, ok := u.args["param"]; ok {
+		values := parsed.Query()
+		if !values.Has(param) {
+			return nil, nil
+		}
+		return values.Get(param), nil
+	}
+
+	switch u.args["part"] {
+	case "scheme":
+		return parsed.Scheme, nil
+	case "host":
+		return parsed.Host, nil
+	case "hostname":
+		return parsed.Hostname(), nil
+	case "port":
+		return parsed.Port(), nil
+	case "path":
+		return parsed.Path, nil
+	case "query":
+		return parsed.RawQuery, nil
+	case "fragment":
+		return parsed.Fragment, nil
+	}
+	return nil, errors.New("unknown error in urlParse")
+}
+
+// requiredArgs checks the given args map to make sure it contains the required args
+// and only the required args.
+func requiredArgs(required []string, args map[string]string) error {
+	if len(args) != len(required) {
+		return fmt.Errorf("expected args %v but got %d args", required, len(args))
+	}
+	for _, arg := range required {
+		if _, ok := args[arg]; !ok {
+			return fmt.Errorf("argument %q is required", arg)
+		}
+	}
+	return nil
+}
+
+// splitArg splits a comma separated argument into its trimmed, non-empty values.
+func splitArg(arg string) []string {
+	var values []string
+	for _, value := range strings.Split(arg, ",") {
+		if value = strings.TrimSpace(value); value != "" {
+			values = append(values, value)
+		}
+	}
+	return values
+}
+
+// allowedArgs checks the given args map to make sure it contains the required args and that any other args are among
+// the optional args.
+func allowedArgs(required, optional []string, args map[string]string) error {
+	for _, arg := range required {
+		if _, ok := args[arg]; !ok {
+			return fmt.Errorf("argument %q is required", arg)
+		}
+	}
+	for arg := range args {
+		if !slices.Contains(required, arg) && !slices.Contains(optional, arg) {
+			return fmt.Errorf("unexpected argument %q, expected args %v and optional args %v", arg, required, optional)
 		}
 	}
 	return nil
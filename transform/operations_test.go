@@ -1,6 +1,8 @@
 package transform
 
 import (
+	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"reflect"
@@ -35,9 +37,35 @@ func (op *testOp) transform(in interface{}) (interface{}, error) {
 	return op.args["out"], nil
 }
 
+// mapResolver is an in memory Resolver which counts the lookups for each key.
+type mapResolver struct {
+	values  map[string]interface{}
+	lookups map[string]int
+}
+
+func (m *mapResolver) Resolve(ctx context.Context, key string) (interface{}, error) {
+	if key == "fail" {
+		return nil, errors.New("fail")
+	}
+	m.lookups[key]++
+	return m.values[key], nil
+}
+
+func newMapResolver() *mapResolver {
+	return &mapResolver{
+		values: map[string]interface{}{
+			"1": map[string]interface{}{"name": "Jane Doe", "title": "Reporter"},
+			"2": map[string]interface{}{"name": "John Doe"},
+		},
+		lookups: make(map[string]int),
+	}
+}
+
 type opTests struct {
 	description string
 	args        map[string]string
+	schema      json.RawMessage
+	call        *transformCall
 	in          interface{}
 	want        interface{}
 	wantErr     bool
@@ -56,6 +84,13 @@ func runOpTests(t *testing.T, opType func() transformOperation, tests []opTests)
 func runOpTest(t *testing.T, opType func() transformOperation, test opTests) {
 	op := opType()
 	err := op.init(test.args)
+	if sop, ok := op.(schemaOperation); ok && err == nil {
+		schema := test.schema
+		if schema == nil {
+			schema = json.RawMessage(`{}`)
+		}
+		err = sop.initSchema(schema)
+	}
 
 	if err := compareWantErrs(err, test.wantInitErr); err != nil {
 		t.Fatal(err)
@@ -63,7 +98,7 @@ func runOpTest(t *testing.T, opType func() transformOperation, test opTests) {
 	if test.wantInitErr {
 		return
 	}
-	got, err := op.transform(test.in)
+	got, err := test.call.operate(op, test.in)
 
 	if err := compareWantErrs(err, test.wantErr); err != nil {
 		t.Fatal(err)
@@ -473,6 +508,12 @@ func TestCurrentTime(t *testing.T) {
 			args:        map[string]string{"format": "RFC3339"},
 			want:        time.Now().Format(time.RFC3339),
 		},
+		{
+			description: "Fixed clock",
+			args:        map[string]string{"format": "RFC3339"},
+			call:        &transformCall{clock: FixedClock(time.Date(2019, 5, 16, 21, 0, 0, 0, time.UTC))},
+			want:        "2019-05-16T21:00:00Z",
+		},
 	}
 
 	runOpTests(t, func() transformOperation { return &currentTime{} }, tests)
@@ -500,6 +541,433 @@ func TestRemoveHTML(t *testing.T) {
 	runOpTests(t, func() transformOperation { return &removeHTML{} }, tests)
 }
 
+func TestSanitizeHTML(t *testing.T) {
+	tests := []opTests{
+		{
+			description: "Allowed tags and attributes",
+			args:        map[string]string{"tags": "p,a,em", "attributes": "a:href"},
+			in:          `<div class="body"><p style="color:red">Read <a href="https://example.com" onclick="x()" title="t">this</a> <em>now</em><script>alert(1)</script></p></div>`,
+			want:        `<p>Read <a href="https://example.com">this</a> <em>now</em></p>`,
+		},
+		{
+			description: "Global attribute",
+			args:        map[string]string{"tags": "p,span", "attributes": "dir"},
+			in:          `<p dir="ltr"><span dir="rtl" lang="ar">text</span></p>`,
+			want:        `<p dir="ltr"><span dir="rtl">text</span></p>`,
+		},
+		{
+			description: "Unsafe URL scheme",
+			args:        map[string]string{"tags": "a", "attributes": "a:href"},
+			in:          `<a href="javascript:alert(1)">link</a>`,
+			want:        `link`,
+		},
+		{
+			description: "Default policy",
+			in:          `<p onclick="x()">Hello <b>world</b></p><iframe src="https://example.com"></iframe>`,
+			want:        `<p>Hello <b>world</b></p>`,
+		},
+		{
+			description: "Invalid attribute",
+			args:        map[string]string{"tags": "a", "attributes": ":href"},
+			wantInitErr: true,
+		},
+		{
+			description: "Non-string input",
+			args:        map[string]string{"tags": "p"},
+			in:          1,
+			wantErr:     true,
+		},
+	}
+
+	runOpTests(t, func() transformOperation { return &sanitizeHTML{} }, tests)
+}
+
+func TestHTMLToText(t *testing.T) {
+	tests := []opTests{
+		{
+			description: "Paragraphs and line breaks",
+			in:          "<p dir=\"ltr\">First   paragraph,\n wrapped.</p><p>Second<br>line &amp; more&nbsp;text</p>",
+			want:        "First paragraph, wrapped.\n\nSecond\nline & more\u00a0text",
+		},
+		{
+			description: "Inline elements keep their spacing",
+			in:          "<div><h1>Title</h1><p>Some <em>emphasized</em> <a href=\"#\">link</a>.</p></div>",
+			want:        "Title\n\nSome emphasized link.",
+		},
+		{
+			description: "Lists, scripts and styles",
+			in:          "<style>p{}</style><ul><li>one</li><li>two</li></ul><script>var a = 1;</script>after",
+			want:        "one\n\ntwo\n\nafter",
+		},
+		{
+			description: "Plain text",
+			in:          "no html &lt;here&gt;",
+			want:        "no html <here>",
+		},
+		{
+			description: "Non-string input",
+			in:          []interface{}{},
+			wantErr:     true,
+		},
+	}
+
+	runOpTests(t, func() transformOperation { return &htmlToText{} }, tests)
+}
+
+func TestHTMLExtract(t *testing.T) {
+	tests := []opTests{
+		{
+			description: "Image sources",
+			args:        map[string]string{"tag": "img", "attribute": "src"},
+			in:          `<p><img src="/a.jpg" alt="a"><IMG SRC="/b.jpg"/><img alt="no src"></p><a href="/c">c</a>`,
+			want:        []interface{}{"/a.jpg", "/b.jpg"},
+		},
+		{
+			description: "No matches",
+			args:        map[string]string{"tag": "video", "attribute": "src"},
+			in:          `<p>text</p>`,
+			want:        []interface{}{},
+		},
+		{
+			description: "Missing attribute arg",
+			args:        map[string]string{"tag": "img"},
+			wantInitErr: true,
+		},
+		{
+			description: "Non-string input",
+			args:        map[string]string{"tag": "img", "attribute": "src"},
+			in:          5,
+			wantErr:     true,
+		},
+	}
+
+	runOpTests(t, func() transformOperation { return &htmlExtract{} }, tests)
+}
+
+func TestHash(t *testing.T) {
+	tests := []opTests{
+		{
+			description: "sha1",
+			args:        map[string]string{"algorithm": "sha1"},
+			in:          "hello",
+			want:        "aaf4c61ddcc5e8a2dabede0f3b482cd9aea9434d",
+		},
+		{
+			description: "sha256 base64",
+			args:        map[string]string{"algorithm": "sha256", "encoding": "base64"},
+			in:          "hello",
+			want:        "LPJNul+wow4m6DsqxbninhsWHlwfp0JecwQzYpOLmCQ=",
+		},
+		{
+			description: "xxhash",
+			args:        map[string]string{"algorithm": "xxhash", "encoding": "hex"},
+			in:          "hello",
+			want:        "26c7827d889f6da3",
+		},
+		{
+			description: "Number input",
+			args:        map[string]string{"algorithm": "sha256"},
+			in:          42,
+			want:        "73475cb40a568e8da8a045ced110137e159f890ac4da883b6b17dc651b3a8049",
+		},
+		{
+			description: "Missing algorithm",
+			args:        map[string]string{"encoding": "hex"},
+			wantInitErr: true,
+		},
+		{
+			description: "Unknown algorithm",
+			args:        map[string]string{"algorithm": "md5"},
+			wantInitErr: true,
+		},
+		{
+			description: "Unknown encoding",
+			args:        map[string]string{"algorithm": "sha1", "encoding": "base32"},
+			wantInitErr: true,
+		},
+		{
+			description: "Object input",
+			args:        map[string]string{"algorithm": "sha1"},
+			in:          map[string]interface{}{"a": "b"},
+			wantErr:     true,
+		},
+	}
+
+	runOpTests(t, func() transformOperation { return &hash{} }, tests)
+}
+
+func TestUUIDv5(t *testing.T) {
+	tests := []opTests{
+		{
+			description: "Predefined namespace",
+			args:        map[string]string{"namespace": "dns"},
+			in:          "www.example.com",
+			want:        "2ed6657d-e927-568b-95e1-2665a8aea6a2",
+		},
+		{
+			description: "Custom namespace",
+			args:        map[string]string{"namespace": "c72f9ed4-6a5b-4c11-9a2b-0e0b7c7a9d3e"},
+			in:          "story/42",
+			want:        "8fb03a66-1dfd-596b-8069-003b2722ea48",
+		},
+		{
+			description: "Invalid namespace",
+			args:        map[string]string{"namespace": "c72f9ed46a5b4c119a2b0e0b7c7a9d3e"},
+			wantInitErr: true,
+		},
+		{
+			description: "Missing namespace",
+			args:        map[string]string{},
+			wantInitErr: true,
+		},
+		{
+			description: "Array input",
+			args:        map[string]string{"namespace": "url"},
+			in:          []interface{}{"a", "b"},
+			wantErr:     true,
+		},
+	}
+
+	runOpTests(t, func() transformOperation { return &uuidv5{} }, tests)
+}
+
+func TestResolve(t *testing.T) {
+	call := newTransformCall(context.Background(), TransformerArgs{
+		Resolvers: map[string]Resolver{"authors": newMapResolver()},
+	})
+
+	tests := []opTests{
+		{
+			description: "Whole value",
+			args:        map[string]string{"resolver": "authors"},
+			call:        call,
+			in:          "2",
+			want:        map[string]interface{}{"name": "John Doe"},
+		},
+		{
+			description: "Return part of the value",
+			args:        map[string]string{"resolver": "authors", "return": "@.name"},
+			call:        call,
+			in:          "1",
+			want:        "Jane Doe",
+		},
+		{
+			description: "Number key",
+			args:        map[string]string{"resolver": "authors", "return": "@.name"},
+			call:        call,
+			in:          2,
+			want:        "John Doe",
+		},
+		{
+			description: "Array of keys",
+			args:        map[string]string{"resolver": "authors", "return": "@.name"},
+			call:        call,
+			in:          []interface{}{"1", "3", "2"},
+			want:        []interface{}{"Jane Doe", "John Doe"},
+		},
+		{
+			description: "Key not found",
+			args:        map[string]string{"resolver": "authors", "return": "@.name"},
+			call:        call,
+			in:          "3",
+			want:        nil,
+		},
+		{
+			description: "Missing return field",
+			args:        map[string]string{"resolver": "authors", "return": "@.title"},
+			call:        call,
+			in:          "2",
+			want:        nil,
+		},
+		{
+			description: "Resolver error",
+			args:        map[string]string{"resolver": "authors"},
+			call:        call,
+			in:          "fail",
+			wantErr:     true,
+		},
+		{
+			description: "Unknown resolver",
+			args:        map[string]string{"resolver": "tags"},
+			call:        call,
+			in:          "1",
+			wantErr:     true,
+		},
+		{
+			description: "No transform call",
+			args:        map[string]string{"resolver": "authors"},
+			in:          "1",
+			wantErr:     true,
+		},
+		{
+			description: "Object key",
+			args:        map[string]string{"resolver": "authors"},
+			call:        call,
+			in:          map[string]interface{}{"id": "1"},
+			wantErr:     true,
+		},
+		{
+			description: "Missing resolver arg",
+			args:        map[string]string{"return": "@.name"},
+			wantInitErr: true,
+		},
+		{
+			description: "Absolute return path",
+			args:        map[string]string{"resolver": "authors", "return": "$.name"},
+			wantInitErr: true,
+		},
+	}
+
+	runOpTests(t, func() transformOperation { return &resolve{} }, tests)
+}
+
+func TestJoin(t *testing.T) {
+	call := newTransformCall(context.Background(), TransformerArgs{})
+	call.inputs = map[string]interface{}{
+		"asset": map[string]interface{}{
+			"items": []interface{}{
+				map[string]interface{}{"id": "1", "title": "Crowds", "credit": "AP"},
+				map[string]interface{}{"id": int64(2), "title": "Aftermath"},
+			},
+		},
+	}
+	items := []interface{}{
+		map[string]interface{}{"assetId": "1", "credit": "USA TODAY"},
+		map[string]interface{}{"assetId": "2"},
+		map[string]interface{}{"assetId": "3"},
+	}
+
+	tests := []opTests{
+		{
+			description: "Left join",
+			args:        map[string]string{"with": "$inputs.asset.items", "on": "@.assetId", "withOn": "@.id"},
+			call:        call,
+			in:          items,
+			want: []interface{}{
+				map[string]interface{}{"assetId": "1", "id": "1", "title": "Crowds", "credit": "USA TODAY"},
+				map[string]interface{}{"assetId": "2", "id": int64(2), "title": "Aftermath"},
+				map[string]interface{}{"assetId": "3"},
+			},
+		},
+		{
+			description: "Inner join",
+			args:        map[string]string{"with": "$inputs.asset.items", "on": "@.assetId", "withOn": "@.id", "type": "inner"},
+			call:        call,
+			in:          items,
+			want: []interface{}{
+				map[string]interface{}{"assetId": "1", "id": "1", "title": "Crowds", "credit": "USA TODAY"},
+				map[string]interface{}{"assetId": "2", "id": int64(2), "title": "Aftermath"},
+			},
+		},
+		{
+			description: "Join as a key",
+			args:        map[string]string{"with": "$inputs.asset.items", "on": "@.assetId", "withOn": "@.id", "as": "asset", "type": "inner"},
+			call:        call,
+			in:          items[:1],
+			want: []interface{}{
+				map[string]interface{}{"assetId": "1", "credit": "USA TODAY", "asset": map[string]interface{}{"id": "1", "title": "Crowds", "credit": "AP"}},
+			},
+		},
+		{
+			description: "Same key name",
+			args:        map[string]string{"with": "$inputs.asset.items", "on": "@.id"},
+			call:        call,
+			in:          []interface{}{map[string]interface{}{"id": "2"}},
+			want:        []interface{}{map[string]interface{}{"id": "2", "title": "Aftermath"}},
+		},
+		{
+			description: "Missing input",
+			args:        map[string]string{"with": "$inputs.taxonomy.items", "on": "@.id"},
+			call:        call,
+			in:          []interface{}{map[string]interface{}{"id": "2"}},
+			wantErr:     true,
+		},
+		{
+			description: "With is not an array",
+			args:        map[string]string{"with": "$inputs.asset", "on": "@.id"},
+			call:        call,
+			in:          []interface{}{map[string]interface{}{"id": "2"}},
+			wantErr:     true,
+		},
+		{
+			description: "No named inputs",
+			args:        map[string]string{"with": "$inputs.asset.items", "on": "@.id"},
+			call:        newTransformCall(context.Background(), TransformerArgs{}),
+			in:          []interface{}{map[string]interface{}{"id": "2"}},
+			wantErr:     true,
+		},
+		{
+			description: "Outside a Transformer",
+			args:        map[string]string{"with": "$inputs.asset.items", "on": "@.id"},
+			in:          []interface{}{map[string]interface{}{"id": "2"}},
+			wantErr:     true,
+		},
+		{
+			description: "Not an array",
+			args:        map[string]string{"with": "$inputs.asset.items", "on": "@.id"},
+			call:        call,
+			in:          map[string]interface{}{"id": "2"},
+			wantErr:     true,
+		},
+		{
+			description: "Scalar items without a match",
+			args:        map[string]string{"with": "$inputs.asset.items", "on": "@"},
+			call:        call,
+			in:          []interface{}{"1"},
+			want:        []interface{}{"1"},
+		},
+		{
+			description: "With not an inputs path",
+			args:        map[string]string{"with": "$.items", "on": "@.id"},
+			wantInitErr: true,
+		},
+		{
+			description: "Absolute on path",
+			args:        map[string]string{"with": "$inputs.asset.items", "on": "$.id"},
+			wantInitErr: true,
+		},
+		{
+			description: "Unknown type",
+			args:        map[string]string{"with": "$inputs.asset.items", "on": "@.id", "type": "outer"},
+			wantInitErr: true,
+		},
+	}
+
+	runOpTests(t, func() transformOperation { return &join{} }, tests)
+}
+
+func TestResolveCache(t *testing.T) {
+	resolver := newMapResolver()
+	args := TransformerArgs{Resolvers: map[string]Resolver{"authors": resolver}}
+	op := &resolve{}
+	if err := op.init(map[string]string{"resolver": "authors", "return": "@.name"}); err != nil {
+		t.Fatal(err)
+	}
+
+	call := newTransformCall(context.Background(), args)
+	for i := 0; i < 3; i++ {
+		if _, err := call.operate(op, "1"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if resolver.lookups["1"] != 1 {
+		t.Errorf("got %d lookups within a call, want 1", resolver.lookups["1"])
+	}
+
+	if _, err := newTransformCall(context.Background(), args).operate(op, "1"); err != nil {
+		t.Fatal(err)
+	}
+	if resolver.lookups["1"] != 2 {
+		t.Errorf("got %d lookups after a new call, want 2", resolver.lookups["1"])
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := newTransformCall(ctx, args).operate(op, "2"); err == nil {
+		t.Error("got nil, want error for a canceled context")
+	}
+}
+
 func TestConvertToFloat64(t *testing.T) {
 	tests := []opTests{
 		{
@@ -655,6 +1123,635 @@ func TestConvertToBool(t *testing.T) {
 	runOpTests(t, func() transformOperation { return &convertToBool{} }, tests)
 }
 
+func TestMultiply(t *testing.T) {
+	tests := []opTests{
+		{
+			description: "Simple working case",
+			args:        map[string]string{"by": "2.5"},
+			in:          4,
+			want:        float64(10),
+		},
+		{
+			description: "Numeric string input",
+			args:        map[string]string{"by": "-1"},
+			in:          "12.5",
+			want:        float64(-12.5),
+		},
+		{
+			description: "Missing arg",
+			args:        map[string]string{},
+			wantInitErr: true,
+		},
+		{
+			description: "Non-numeric arg",
+			args:        map[string]string{"by": "two"},
+			wantInitErr: true,
+		},
+		{
+			description: "Non-numeric input",
+			args:        map[string]string{"by": "2"},
+			in:          "two",
+			wantErr:     true,
+		},
+	}
+
+	runOpTests(t, func() transformOperation { return &multiply{} }, tests)
+}
+
+func TestDivide(t *testing.T) {
+	tests := []opTests{
+		{
+			description: "Simple working case",
+			args:        map[string]string{"by": "4"},
+			in:          float64(10),
+			want:        float64(2.5),
+		},
+		{
+			description: "Divide by zero",
+			args:        map[string]string{"by": "0"},
+			wantInitErr: true,
+		},
+		{
+			description: "Too many args",
+			args:        map[string]string{"by": "4", "precision": "2"},
+			wantInitErr: true,
+		},
+		{
+			description: "Non-numeric input",
+			args:        map[string]string{"by": "4"},
+			in:          true,
+			wantErr:     true,
+		},
+	}
+
+	runOpTests(t, func() transformOperation { return &divide{} }, tests)
+}
+
+func TestAdd(t *testing.T) {
+	tests := []opTests{
+		{
+			description: "Simple working case",
+			args:        map[string]string{"value": "1.5"},
+			in:          int64(2),
+			want:        float64(3.5),
+		},
+		{
+			description: "Subtract",
+			args:        map[string]string{"value": "-10"},
+			in:          []interface{}{float64(4)},
+			want:        float64(-6),
+		},
+		{
+			description: "Missing arg",
+			args:        map[string]string{"by": "1"},
+			wantInitErr: true,
+		},
+	}
+
+	runOpTests(t, func() transformOperation { return &add{} }, tests)
+}
+
+func TestRound(t *testing.T) {
+	tests := []opTests{
+		{
+			description: "Default precision",
+			in:          float64(2.5),
+			want:        float64(3),
+		},
+		{
+			description: "Negative number",
+			in:          float64(-2.5),
+			want:        float64(-3),
+		},
+		{
+			description: "Precision of 2",
+			args:        map[string]string{"precision": "2"},
+			in:          float64(1.23456),
+			want:        float64(1.23),
+		},
+		{
+			description: "Integer input",
+			args:        map[string]string{"precision": "1"},
+			in:          7,
+			want:        float64(7),
+		},
+		{
+			description: "Negative precision",
+			args:        map[string]string{"precision": "-1"},
+			wantInitErr: true,
+		},
+		{
+			description: "Unknown arg",
+			args:        map[string]string{"places": "1"},
+			wantInitErr: true,
+		},
+	}
+
+	runOpTests(t, func() transformOperation { return &round{} }, tests)
+}
+
+func TestClamp(t *testing.T) {
+	tests := []opTests{
+		{
+			description: "Within range",
+			args:        map[string]string{"min": "0", "max": "10"},
+			in:          5,
+			want:        float64(5),
+		},
+		{
+			description: "Below min",
+			args:        map[string]string{"min": "0", "max": "10"},
+			in:          -5,
+			want:        float64(0),
+		},
+		{
+			description: "Above max",
+			args:        map[string]string{"min": "0", "max": "10"},
+			in:          float64(10.5),
+			want:        float64(10),
+		},
+		{
+			description: "Only max",
+			args:        map[string]string{"max": "100"},
+			in:          float64(-1000),
+			want:        float64(-1000),
+		},
+		{
+			description: "No args",
+			args:        map[string]string{},
+			wantInitErr: true,
+		},
+		{
+			description: "Min larger than max",
+			args:        map[string]string{"min": "10", "max": "0"},
+			wantInitErr: true,
+		},
+	}
+
+	runOpTests(t, func() transformOperation { return &clamp{} }, tests)
+}
+
+func TestConvertUnit(t *testing.T) {
+	tests := []opTests{
+		{
+			description: "Bytes to MB",
+			args:        map[string]string{"from": "B", "to": "MB"},
+			in:          2500000,
+			want:        float64(2.5),
+		},
+		{
+			description: "Bytes to MiB",
+			args:        map[string]string{"from": "B", "to": "MiB"},
+			in:          1048576,
+			want:        float64(1),
+		},
+		{
+			description: "Milliseconds to seconds",
+			args:        map[string]string{"from": "ms", "to": "s"},
+			in:          "1500",
+			want:        float64(1.5),
+		},
+		{
+			description: "kbps to Mbps",
+			args:        map[string]string{"from": "kbps", "to": "Mbps"},
+			in:          float64(4500),
+			want:        float64(4.5),
+		},
+		{
+			description: "Mismatched dimensions",
+			args:        map[string]string{"from": "ms", "to": "MB"},
+			wantInitErr: true,
+		},
+		{
+			description: "Unknown unit",
+			args:        map[string]string{"from": "furlong", "to": "s"},
+			wantInitErr: true,
+		},
+		{
+			description: "Non-numeric input",
+			args:        map[string]string{"from": "ms", "to": "s"},
+			in:          "soon",
+			wantErr:     true,
+		},
+	}
+
+	runOpTests(t, func() transformOperation { return &convertUnit{} }, tests)
+}
+
+func TestTrim(t *testing.T) {
+	tests := []opTests{
+		{
+			description: "Whitespace",
+			in:          " \t a string \n",
+			want:        "a string",
+		},
+		{
+			description: "Chars",
+			args:        map[string]string{"chars": "/"},
+			in:          "/path/to/",
+			want:        "path/to",
+		},
+		{
+			description: "Unknown arg",
+			args:        map[string]string{"cutset": "/"},
+			wantInitErr: true,
+		},
+		{
+			description: "Non-string input",
+			in:          5,
+			wantErr:     true,
+		},
+	}
+
+	runOpTests(t, func() transformOperation { return &trim{} }, tests)
+}
+
+func TestCollapseWhitespace(t *testing.T) {
+	tests := []opTests{
+		{
+			description: "Mixed whitespace",
+			in:          "  a \n\n b\t\tc  ",
+			want:        "a b c",
+		},
+		{
+			description: "Only whitespace",
+			in:          " \n ",
+			want:        "",
+		},
+		{
+			description: "Non-string input",
+			in:          true,
+			wantErr:     true,
+		},
+	}
+
+	runOpTests(t, func() transformOperation { return &collapseWhitespace{} }, tests)
+}
+
+func TestTruncate(t *testing.T) {
+	tests := []opTests{
+		{
+			description: "Short enough",
+			args:        map[string]string{"length": "20"},
+			in:          "A short headline",
+			want:        "A short headline",
+		},
+		{
+			description: "Word boundary with ellipsis",
+			args:        map[string]string{"length": "20"},
+			in:          "Council approves the new budget",
+			want:        "Council approves...",
+		},
+		{
+			description: "Cut at a space",
+			args:        map[string]string{"length": "11", "ellipsis": ""},
+			in:          "Council approves",
+			want:        "Council",
+		},
+		{
+			description: "No word boundary",
+			args:        map[string]string{"length": "12", "wordBoundary": "false", "ellipsis": "…"},
+			in:          "Council approves",
+			want:        "Council app…",
+		},
+		{
+			description: "Multibyte characters",
+			args:        map[string]string{"length": "4", "ellipsis": ""},
+			in:          "ÀÉÎÕÜ",
+			want:        "ÀÉÎÕ",
+		},
+		{
+			description: "Length from the schema maxLength",
+			schema:      json.RawMessage(`{"type":"string","maxLength":10}`),
+			in:          "Council approves the new budget",
+			want:        "Council...",
+		},
+		{
+			description: "Length argument overrides maxLength",
+			args:        map[string]string{"length": "19"},
+			schema:      json.RawMessage(`{"type":"string","maxLength":10}`),
+			in:          "Council approves the new budget",
+			want:        "Council approves...",
+		},
+		{
+			description: "No length nor maxLength",
+			schema:      json.RawMessage(`{"type":"string"}`),
+			wantInitErr: true,
+		},
+		{
+			description: "Invalid length",
+			args:        map[string]string{"length": "-1"},
+			wantInitErr: true,
+		},
+		{
+			description: "Invalid wordBoundary",
+			args:        map[string]string{"length": "10", "wordBoundary": "sometimes"},
+			wantInitErr: true,
+		},
+		{
+			description: "Non-string input",
+			args:        map[string]string{"length": "10"},
+			in:          10,
+			wantErr:     true,
+		},
+	}
+
+	runOpTests(t, func() transformOperation { return &truncate{} }, tests)
+}
+
+func TestPrefixSuffix(t *testing.T) {
+	prefixTests := []opTests{
+		{
+			description: "Simple working case",
+			args:        map[string]string{"value": "https://www.example.com"},
+			in:          "/story/1",
+			want:        "https://www.example.com/story/1",
+		},
+		{
+			description: "Missing arg",
+			args:        map[string]string{},
+			wantInitErr: true,
+		},
+		{
+			description: "Non-string input",
+			args:        map[string]string{"value": "a"},
+			in:          1,
+			wantErr:     true,
+		},
+	}
+	runOpTests(t, func() transformOperation { return &prefix{} }, prefixTests)
+
+	suffixTests := []opTests{
+		{
+			description: "Simple working case",
+			args:        map[string]string{"value": ".jpg"},
+			in:          "image",
+			want:        "image.jpg",
+		},
+		{
+			description: "Too many args",
+			args:        map[string]string{"value": "a", "other": "b"},
+			wantInitErr: true,
+		},
+	}
+	runOpTests(t, func() transformOperation { return &suffix{} }, suffixTests)
+}
+
+func TestPad(t *testing.T) {
+	tests := []opTests{
+		{
+			description: "Left pad a number",
+			args:        map[string]string{"length": "5", "char": "0"},
+			in:          int64(42),
+			want:        "00042",
+		},
+		{
+			description: "Right pad with spaces",
+			args:        map[string]string{"length": "6", "side": "right"},
+			in:          "ab",
+			want:        "ab    ",
+		},
+		{
+			description: "Already long enough",
+			args:        map[string]string{"length": "2"},
+			in:          "abcd",
+			want:        "abcd",
+		},
+		{
+			description: "Multibyte input",
+			args:        map[string]string{"length": "4", "char": "·"},
+			in:          "été",
+			want:        "·été",
+		},
+		{
+			description: "Object input",
+			args:        map[string]string{"length": "4"},
+			in:          map[string]interface{}{},
+			wantErr:     true,
+		},
+		{
+			description: "Invalid length",
+			args:        map[string]string{"length": "0"},
+			wantInitErr: true,
+		},
+		{
+			description: "Multiple characters",
+			args:        map[string]string{"length": "4", "char": "ab"},
+			wantInitErr: true,
+		},
+		{
+			description: "Unknown side",
+			args:        map[string]string{"length": "4", "side": "both"},
+			wantInitErr: true,
+		},
+	}
+
+	runOpTests(t, func() transformOperation { return &pad{} }, tests)
+}
+
+func TestTemplate(t *testing.T) {
+	tests := []opTests{
+		{
+			description: "Scalar value",
+			args:        map[string]string{"template": "https://www.example.com/story/{{.}}/"},
+			in:          "123",
+			want:        "https://www.example.com/story/123/",
+		},
+		{
+			description: "Object value",
+			args:        map[string]string{"template": "{{.first}} {{.last}}"},
+			in:          map[string]interface{}{"first": "Jane", "last": "Doe"},
+			want:        "Jane Doe",
+		},
+		{
+			description: "Missing key",
+			args:        map[string]string{"template": "{{.first}} {{.middle}}"},
+			in:          map[string]interface{}{"first": "Jane"},
+			wantErr:     true,
+		},
+		{
+			description: "Invalid template",
+			args:        map[string]string{"template": "{{.first"},
+			wantInitErr: true,
+		},
+		{
+			description: "Missing arg",
+			args:        map[string]string{},
+			wantInitErr: true,
+		},
+	}
+
+	runOpTests(t, func() transformOperation { return &template{} }, tests)
+}
+
+func TestURLEncodeDecode(t *testing.T) {
+	encodeTests := []opTests{
+		{
+			description: "Query mode",
+			in:          "a b&c=d/e",
+			want:        "a+b%26c%3Dd%2Fe",
+		},
+		{
+			description: "Path mode",
+			args:        map[string]string{"mode": "path"},
+			in:          "a b&c=d/e",
+			want:        "a%20b&c=d%2Fe",
+		},
+		{
+			description: "Invalid mode",
+			args:        map[string]string{"mode": "fragment"},
+			wantInitErr: true,
+		},
+	}
+	runOpTests(t, func() transformOperation { return &urlEncode{} }, encodeTests)
+
+	decodeTests := []opTests{
+		{
+			description: "Query mode",
+			in:          "a+b%26c%3Dd%2Fe",
+			want:        "a b&c=d/e",
+		},
+		{
+			description: "Path mode",
+			args:        map[string]string{"mode": "path"},
+			in:          "a+b%20c",
+			want:        "a+b c",
+		},
+		{
+			description: "Invalid escape",
+			in:          "%zz",
+			wantErr:     true,
+		},
+	}
+	runOpTests(t, func() transformOperation { return &urlDecode{} }, decodeTests)
+}
+
+func TestBase64Decode(t *testing.T) {
+	tests := []opTests{
+		{
+			description: "Standard encoding",
+			in:          "aGVsbG8gd29ybGQ=",
+			want:        "hello world",
+		},
+		{
+			description: "Raw URL encoding",
+			args:        map[string]string{"encoding": "rawURL"},
+			in:          "PDw_Pz4-",
+			want:        "<<??>>",
+		},
+		{
+			description: "Unknown encoding",
+			args:        map[string]string{"encoding": "base32"},
+			wantInitErr: true,
+		},
+		{
+			description: "Invalid input",
+			in:          "not base64!",
+			wantErr:     true,
+		},
+	}
+
+	runOpTests(t, func() transformOperation { return &base64Decode{} }, tests)
+}
+
+func TestJSONParse(t *testing.T) {
+	tests := []opTests{
+		{
+			description: "Object",
+			in:          `{"a": "b", "c": [1, 2]}`,
+			want:        map[string]interface{}{"a": "b", "c": []interface{}{float64(1), float64(2)}},
+		},
+		{
+			description: "Exact numbers",
+			in:          `[1.5, 9007199254740993, 123456789012345678901234, 0.12345678901234567890]`,
+			want:        []interface{}{1.5, int64(9007199254740993), json.Number("123456789012345678901234"), json.Number("0.12345678901234567890")},
+		},
+		{
+			description: "String",
+			in:          `"quoted"`,
+			want:        "quoted",
+		},
+		{
+			description: "Invalid JSON",
+			in:          `{"a":`,
+			wantErr:     true,
+		},
+		{
+			description: "Non-string input",
+			in:          map[string]interface{}{},
+			wantErr:     true,
+		},
+	}
+
+	runOpTests(t, func() transformOperation { return &jsonParse{} }, tests)
+}
+
+func TestURLParse(t *testing.T) {
+	const testURL = "https://www.example.com:8443/story/news/1?id=42&utm_source=feed#comments"
+	tests := []opTests{
+		{
+			description: "Host",
+			args:        map[string]string{"part": "host"},
+			in:          testURL,
+			want:        "www.example.com:8443",
+		},
+		{
+			description: "Hostname",
+			args:        map[string]string{"part": "hostname"},
+			in:          testURL,
+			want:        "www.example.com",
+		},
+		{
+			description: "Path",
+			args:        map[string]string{"part": "path"},
+			in:          testURL,
+			want:        "/story/news/1",
+		},
+		{
+			description: "Query",
+			args:        map[string]string{"part": "query"},
+			in:          testURL,
+			want:        "id=42&utm_source=feed",
+		},
+		{
+			description: "Fragment",
+			args:        map[string]string{"part": "fragment"},
+			in:          testURL,
+			want:        "comments",
+		},
+		{
+			description: "Query parameter",
+			args:        map[string]string{"param": "id"},
+			in:          testURL,
+			want:        "42",
+		},
+		{
+			description: "Missing query parameter",
+			args:        map[string]string{"param": "page"},
+			in:          testURL,
+			want:        nil,
+		},
+		{
+			description: "Both part and param",
+			args:        map[string]string{"part": "host", "param": "id"},
+			wantInitErr: true,
+		},
+		{
+			description: "Unknown part",
+			args:        map[string]string{"part": "user"},
+			wantInitErr: true,
+		},
+		{
+			description: "Invalid URL",
+			args:        map[string]string{"part": "host"},
+			in:          "http://[::1",
+			wantErr:     true,
+		},
+	}
+
+	runOpTests(t, func() transformOperation { return &urlParse{} }, tests)
+}
+
 func compareWantErrs(gotErr error, wantErr bool) error {
 	switch {
 	case wantErr && gotErr == nil:
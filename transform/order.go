@@ -0,0 +1,164 @@
+package transform
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/GannettDigital/jsonparser"
+	"github.com/GannettDigital/jstransform/jsonschema"
+)
+
+// propertyOrder holds the order the properties of an instance are declared in the schema, it forms a tree matching
+// the schema and is used to write the transformed JSON with the keys in the same order.
+type propertyOrder struct {
+	names      []string
+	properties map[string]*propertyOrder
+	items      *propertyOrder
+}
+
+// newPropertyOrder builds the propertyOrder tree for the schema.
+func newPropertyOrder(schema *jsonschema.Schema) (*propertyOrder, error) {
+	root := &propertyOrder{names: schema.PropertyOrder}
+	if err := jsonschema.WalkRaw(schema, root.addDescendant); err != nil {
+		return nil, err
+	}
+	return root, nil
+}
+
+// addDescendant adds the propertyOrder for the schema instance at path to the tree, the parent must already be present.
+func (root *propertyOrder) addDescendant(path string, value json.RawMessage) error {
+	splits := strings.Split(strings.Replace(path, "[", ".[", -1), ".")
+	parent := root
+	for _, sp := range splits[1 : len(splits)-1] {
+		if sp == "[*]" {
+			parent = parent.items
+		} else {
+			parent = parent.properties[sp]
+		}
+		if parent == nil {
+			return fmt.Errorf("no parent found for %q", path)
+		}
+	}
+
+	po := &propertyOrder{}
+	if err := jsonparser.ObjectEach(value, func(key []byte, _ []byte, _ jsonparser.ValueType, _ int) error {
+		po.names = append(po.names, string(key))
+		return nil
+	}, "properties"); err != nil && err != jsonparser.KeyPathNotFoundError {
+		return fmt.Errorf("failed to read the properties at %q: %v", path, err)
+	}
+
+	name := splits[len(splits)-1]
+	if name == "[*]" {
+		parent.items = po
+		return nil
+	}
+	if parent.properties == nil {
+		parent.properties = make(map[string]*propertyOrder)
+	}
+	parent.properties[name] = po
+	return nil
+}
+
+// marshal JSON encodes the value with the keys of objects in the order of the schema properties. Keys not in the
+// schema follow in alphabetical order, the same order used by json.Marshal.
+func (po *propertyOrder) marshal(value interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := po.encode(&buf, value); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (po *propertyOrder) encode(buf *bytes.Buffer, value interface{}) error {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		if v == nil {
+			buf.WriteString("null")
+			return nil
+		}
+		buf.WriteByte('{')
+		for i, key := range po.orderKeys(v) {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			rawKey, err := json.Marshal(key)
+			if err != nil {
+				return err
+			}
+			buf.Write(rawKey)
+			buf.WriteByte(':')
+			if err := po.property(key).encode(buf, v[key]); err != nil {
+				return err
+			}
+		}
+		buf.WriteByte('}')
+		return nil
+	case []interface{}:
+		if v == nil {
+			buf.WriteString("null")
+			return nil
+		}
+		items := po.itemsOrder()
+		buf.WriteByte('[')
+		for i, item := range v {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			if err := items.encode(buf, item); err != nil {
+				return err
+			}
+		}
+		buf.WriteByte(']')
+		return nil
+	}
+
+	raw, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	buf.Write(raw)
+	return nil
+}
+
+// orderKeys returns the keys of the object in the order they are written.
+func (po *propertyOrder) orderKeys(object map[string]interface{}) []string {
+	keys := make([]string, 0, len(object))
+	seen := make(map[string]bool, len(object))
+	if po != nil {
+		for _, name := range po.names {
+			if _, ok := object[name]; ok && !seen[name] {
+				keys = append(keys, name)
+				seen[name] = true
+			}
+		}
+	}
+
+	var remaining []string
+	for key := range object {
+		if !seen[key] {
+			remaining = append(remaining, key)
+		}
+	}
+	sort.Strings(remaining)
+	return append(keys, remaining...)
+}
+
+// itemsOrder returns the propertyOrder of the array items or nil if it is not in the schema.
+func (po *propertyOrder) itemsOrder() *propertyOrder {
+	if po == nil {
+		return nil
+	}
+	return po.items
+}
+
+// property returns the propertyOrder of the named property or nil if it is not in the schema.
+func (po *propertyOrder) property(name string) *propertyOrder {
+	if po == nil {
+		return nil
+	}
+	return po.properties[name]
+}
@@ -0,0 +1,58 @@
+package transform
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/GannettDigital/jstransform/jsonschema"
+)
+
+func TestPreserveOrder(t *testing.T) {
+	schema, err := jsonschema.SchemaFromFile("./test_data/property-order.json", "")
+	if err != nil {
+		t.Fatalf("failed to load schema: %v", err)
+	}
+
+	in := json.RawMessage(`
+{
+	"id": "1",
+	"title": "Title",
+	"byline": {"email": "jane@example.com", "name": "Jane"},
+	"images": [{"caption": "A caption", "url": "https://example.com/1.jpg"}, {"url": "https://example.com/2.jpg"}],
+	"extra": {"z": 1, "a": {"y": true, "b": false}}
+}`)
+
+	tests := []struct {
+		description   string
+		preserveOrder bool
+		want          string
+	}{
+		{
+			description: "alphabetical order",
+			want:        `{"byline":{"email":"jane@example.com","name":"Jane"},"extra":{"a":{"b":false,"y":true},"z":1},"id":"1","images":[{"caption":"A caption","url":"https://example.com/1.jpg"},{"url":"https://example.com/2.jpg"}],"title":"Title"}`,
+		},
+		{
+			description:   "schema order",
+			preserveOrder: true,
+			want:          `{"title":"Title","id":"1","byline":{"name":"Jane","email":"jane@example.com"},"images":[{"url":"https://example.com/1.jpg","caption":"A caption"},{"url":"https://example.com/2.jpg"}],"extra":{"a":{"b":false,"y":true},"z":1}}`,
+		},
+	}
+
+	for _, test := range tests {
+		tr, err := NewTransformerWithArgs(schema, "cumulo", TransformerArgs{PreserveOrder: test.preserveOrder})
+		if err != nil {
+			t.Fatalf("Test %q - failed to initialize transformer: %v", test.description, err)
+		}
+
+		// The order of map iteration varies between runs so several are done
+		for i := 0; i < 10; i++ {
+			got, err := tr.Transform(in)
+			if err != nil {
+				t.Fatalf("Test %q - got error, want nil: %v", test.description, err)
+			}
+			if string(got) != test.want {
+				t.Fatalf("Test %q - got\n%s\nwant\n%s", test.description, got, test.want)
+			}
+		}
+	}
+}
@@ -0,0 +1,363 @@
+package transform
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	jsonpath "github.com/GannettDigital/PaesslerAG_jsonpath"
+)
+
+// outputPrefix is the jsonPath prefix used by instructions which read from the output of the transform rather than
+// the input.
+const outputPrefix = "$out."
+
+// arrayIndexRe matches the index of an array item within a path, ie `[3]`.
+var arrayIndexRe = regexp.MustCompile(`\[\d+\]`)
+
+// outputRecorder wraps an instanceTransformer whose output is referenced by a `$out.` jsonPath, saving the output in
+// the call so it can be read by the instructions referencing it. An instance may be transformed early when its
+// output is referenced before its turn, the recorded output is then used rather than transforming it again.
+type outputRecorder struct {
+	instanceTransformer
+}
+
+func (or *outputRecorder) transform(call *transformCall, in interface{}, modifier pathModifier) (interface{}, error) {
+	path := applyModifier(modifier, or.path())
+	if value, ok := call.recordedOutput(path); ok {
+		return value, nil
+	}
+
+	value, err := or.instanceTransformer.transform(call, in, modifier)
+	if err != nil {
+		return nil, err
+	}
+	call.saveOutput(path, value)
+	return value, nil
+}
+
+// outputReference is a `$out.` instruction along with the path of the instance it is part of.
+type outputReference struct {
+	path        string
+	instruction *transformInstruction
+}
+
+// outputPath returns the reference path in the `$out.` form used in the schema.
+func (ref outputReference) outputPath() string {
+	return "$out" + strings.TrimPrefix(ref.instruction.jsonPath, "$")
+}
+
+// linkOutputReferences prepares the instanceTransformer tree for the instructions reading from the output.
+// For each reference the deepest instance along its path is found, this is the instance whose output is recorded.
+// Referenced instances which have not been transformed when they are read are transformed on demand, for an instance
+// within an array not containing the reference the outermost such array is transformed so its items are in place.
+// Errors are returned for references to an instance's own output, the output of a parent or child or for circular
+// references, including those through the children of a referenced instance.
+func (tr *Transformer) linkOutputReferences() error {
+	var references []outputReference
+	eachInstance(tr.root, func(it instanceTransformer) {
+		tis := instanceTransforms(it)
+		if tis == nil {
+			return
+		}
+		for _, from := range tis.From {
+			if from.fromOutput {
+				references = append(references, outputReference{path: it.path(), instruction: from})
+			}
+		}
+	})
+	if len(references) == 0 {
+		return nil
+	}
+
+	instances := make(map[string]instanceTransformer)
+	dependencies := make(map[string][]string) // instance path -> paths of the instances it depends on
+	eachInstance(tr.root, func(it instanceTransformer) {
+		instances[it.path()] = it
+		for _, child := range instanceChildren(it) {
+			dependencies[it.path()] = append(dependencies[it.path()], child.path())
+		}
+	})
+
+	recorders := make(map[string]*outputRecorder)
+	recorder := func(it instanceTransformer) *outputRecorder {
+		if recorders[it.path()] == nil {
+			recorders[it.path()] = &outputRecorder{it}
+		}
+		return recorders[it.path()]
+	}
+	for _, ref := range references {
+		target, targetPath, err := tr.findOutputTarget(ref)
+		if err != nil {
+			return err
+		}
+		if _, fromChild, targetChild := splitCommonPath(ref.path, target.path()); fromChild == "" || targetChild == "" {
+			return fmt.Errorf("the transform for %q can not reference its own output or the output of a parent or child with %q", ref.path, ref.outputPath())
+		}
+
+		unit := tr.outputUnit(ref.path, target)
+		ref.instruction.outputTarget = targetPath
+		ref.instruction.outputUnit = recorder(unit)
+		recorder(target)
+		dependencies[ref.path] = append(dependencies[ref.path], unit.path())
+	}
+
+	if err := checkCircularReferences(instances, dependencies); err != nil {
+		return fmt.Errorf("invalid $out. references: %v", err)
+	}
+
+	for path, recorder := range recorders {
+		parent, err := tr.findParent(path)
+		if err != nil {
+			return err
+		}
+		if err := parent.addChild(recorder); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// outputUnit returns the instance transformed on demand for a reference from the instance at path to the target. It
+// is the outermost array containing the target but not the referencing instance or else the target itself.
+func (tr *Transformer) outputUnit(path string, target instanceTransformer) instanceTransformer {
+	splits := strings.Split(strings.Replace(target.path(), "[", ".[", -1), ".")
+	for i, sp := range splits {
+		if sp != "[*]" {
+			continue
+		}
+		arrayPath := strings.Replace(strings.Join(splits[:i], "."), ".[", "[", -1)
+		if !strings.HasPrefix(path, arrayPath+"[*]") {
+			return tr.findInstance(arrayPath)
+		}
+	}
+	return target
+}
+
+// findOutputTarget finds the deepest instance along the path of a `$out.` reference. The part of the reference path
+// which identifies the instance is returned along with it, the rest of the path is evaluated within its output.
+func (tr *Transformer) findOutputTarget(ref outputReference) (instanceTransformer, string, error) {
+	splits := strings.Split(strings.Replace(ref.instruction.jsonPath, "[", ".[", -1), ".")
+
+	var (
+		target instanceTransformer
+		depth  int
+	)
+	current := tr.root
+	for i, sp := range splits[1:] {
+		if strings.HasPrefix(sp, "[") {
+			current = current.child()
+		} else {
+			current = current.selectChild(sp)
+		}
+		if current == nil {
+			break
+		}
+		target = current
+		depth = i + 2
+	}
+	if target == nil {
+		return nil, "", fmt.Errorf("the transform for %q references %q which is not in the schema", ref.path, ref.outputPath())
+	}
+
+	targetPath := strings.Replace(strings.Join(splits[:depth], "."), ".[", "[", -1)
+	// A wildcard is only replaced by an index when the reference is within the same array item.
+	for i, sp := range splits[:depth] {
+		if sp != "[*]" {
+			continue
+		}
+		arrayPath := strings.Replace(strings.Join(splits[:i+1], "."), ".[", "[", -1)
+		if !strings.HasPrefix(ref.path, arrayPath) {
+			return nil, "", fmt.Errorf("the transform for %q uses a wildcard outside of an array containing it with %q", ref.path, ref.outputPath())
+		}
+	}
+
+	return target, targetPath, nil
+}
+
+// findInstance walks the instanceTransformer tree to find the instance at the given path.
+func (tr *Transformer) findInstance(path string) instanceTransformer {
+	splits := strings.Split(strings.Replace(path, "[", ".[", -1), ".")
+	current := tr.root
+	for _, sp := range splits[1:] {
+		if current == nil {
+			return nil
+		}
+		if strings.HasPrefix(sp, "[") {
+			current = current.child()
+			continue
+		}
+		current = current.selectChild(sp)
+	}
+	return current
+}
+
+// splitCommonPath finds the deepest common parent of two instance paths and returns it along with the name of the
+// child of that parent on the way to each path. The names are empty if one path is the same as or a parent of the other.
+func splitCommonPath(a, b string) (string, string, string) {
+	aSplits := strings.Split(strings.Replace(a, "[", ".[", -1), ".")
+	bSplits := strings.Split(strings.Replace(b, "[", ".[", -1), ".")
+
+	i := 0
+	for i < len(aSplits) && i < len(bSplits) && aSplits[i] == bSplits[i] {
+		i++
+	}
+	common := strings.Replace(strings.Join(aSplits[:i], "."), ".[", "[", -1)
+	if i == len(aSplits) || i == len(bSplits) {
+		return common, "", ""
+	}
+	return common, aSplits[i], bSplits[i]
+}
+
+// checkCircularReferences returns an error when the dependencies between the instances, keyed by path, are circular.
+// The references are evaluated on demand when the output is needed so only the absence of cycles matters.
+func checkCircularReferences(instances map[string]instanceTransformer, dependencies map[string][]string) error {
+	remaining := make(map[string]int) // instance path -> count of dependencies not yet resolved
+	dependents := make(map[string][]string)
+	for path := range instances {
+		remaining[path] = 0
+	}
+	for path, deps := range dependencies {
+		for _, dep := range deps {
+			remaining[path]++
+			dependents[dep] = append(dependents[dep], path)
+		}
+	}
+
+	var ready []string
+	for path, count := range remaining {
+		if count == 0 {
+			ready = append(ready, path)
+		}
+	}
+	for len(ready) > 0 {
+		path := ready[len(ready)-1]
+		ready = ready[:len(ready)-1]
+		delete(remaining, path)
+		for _, dependent := range dependents[path] {
+			if remaining[dependent]--; remaining[dependent] == 0 {
+				ready = append(ready, dependent)
+			}
+		}
+	}
+	if len(remaining) == 0 {
+		return nil
+	}
+
+	circular := make([]string, 0, len(remaining))
+	for path := range remaining {
+		circular = append(circular, path)
+	}
+	sort.Strings(circular)
+	return fmt.Errorf("circular references between %v", circular)
+}
+
+// eachInstance calls fn for the given instanceTransformer and all of those below it in the tree.
+func eachInstance(it instanceTransformer, fn func(instanceTransformer)) {
+	if it == nil {
+		return
+	}
+	fn(it)
+	for _, child := range instanceChildren(it) {
+		eachInstance(child, fn)
+	}
+}
+
+// instanceChildren returns the instanceTransformers directly below the given one in the tree.
+func instanceChildren(it instanceTransformer) []instanceTransformer {
+	if recorder, ok := it.(*outputRecorder); ok {
+		it = recorder.instanceTransformer
+	}
+	switch t := it.(type) {
+	case *arrayTransformer:
+		if t.childTransformer != nil {
+			return []instanceTransformer{t.childTransformer}
+		}
+	case *objectTransformer:
+		children := make([]instanceTransformer, 0, len(t.children))
+		for _, child := range t.children {
+			children = append(children, child)
+		}
+		return children
+	}
+	return nil
+}
+
+// instanceTransforms returns the transform instructions of the instanceTransformer or nil if it has none.
+func instanceTransforms(it instanceTransformer) *transformInstructions {
+	if recorder, ok := it.(*outputRecorder); ok {
+		it = recorder.instanceTransformer
+	}
+	switch t := it.(type) {
+	case *arrayTransformer:
+		return t.transforms
+	case *objectTransformer:
+		return t.transforms
+	case *scalarTransformer:
+		return t.transforms
+	}
+	return nil
+}
+
+// output returns the value at path within the recorded output of the instance at target, path is either the same as
+// target or within it. When the target has not been transformed yet the unit containing it is transformed first.
+func (call *transformCall) output(unit *outputRecorder, in interface{}, target, path string) (interface{}, error) {
+	if call == nil {
+		return nil, nil
+	}
+	value, ok := call.outputs[target]
+	if !ok && unit != nil {
+		if _, err := unit.transform(call, in, indexModifier(unitPath(unit.path(), target))); err != nil && err != errNullValue {
+			return nil, err
+		}
+		value = call.outputs[target]
+	}
+	if value == nil || path == target {
+		return value, nil
+	}
+
+	rawValue, err := jsonpath.Get("$"+strings.TrimPrefix(path, target), value)
+	if err != nil {
+		return nil, nil
+	}
+	return rawValue, nil
+}
+
+// recordedOutput returns the recorded output of the instance at path and whether there is one.
+func (call *transformCall) recordedOutput(path string) (interface{}, bool) {
+	if call == nil {
+		return nil, false
+	}
+	value, ok := call.outputs[path]
+	return value, ok
+}
+
+// saveOutput records the output of the instance at path.
+func (call *transformCall) saveOutput(path string, value interface{}) {
+	if call == nil {
+		return
+	}
+	call.outputs[path] = value
+}
+
+// unitPath returns the path of the unit, ie `$.items`, with the indexes of the target path within it, for the target
+// `$.items[2].title` and the unit `$.items[*].title` it is `$.items[2].title`.
+func unitPath(unit, target string) string {
+	unitSplits := strings.Split(strings.Replace(unit, "[", ".[", -1), ".")
+	targetSplits := strings.Split(strings.Replace(target, "[", ".[", -1), ".")
+	if len(targetSplits) > len(unitSplits) {
+		targetSplits = targetSplits[:len(unitSplits)]
+	}
+	return strings.Replace(strings.Join(targetSplits, "."), ".[", "[", -1)
+}
+
+// indexModifier returns a pathModifier which replaces the wildcards of the arrays along the path with the indexes of
+// the path, the same as the modifiers built when transforming the items of those arrays.
+func indexModifier(path string) pathModifier {
+	var modifier pathModifier
+	for _, loc := range arrayIndexRe.FindAllStringIndex(path, -1) {
+		modifier = pathReplace(path[:loc[0]]+"[*]", path[:loc[1]], modifier)
+	}
+	return modifier
+}
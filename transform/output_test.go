@@ -0,0 +1,207 @@
+package transform
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/GannettDigital/jstransform/jsonschema"
+)
+
+func TestOutputReferences(t *testing.T) {
+	schema, err := jsonschema.SchemaFromFile("./test_data/output-references.json", "")
+	if err != nil {
+		t.Fatalf("failed to load schema: %v", err)
+	}
+	tr, err := NewTransformer(schema, "cumulo")
+	if err != nil {
+		t.Fatalf("failed to initialize transformer: %v", err)
+	}
+
+	in := json.RawMessage(`
+{
+	"title": "Hello, World!",
+	"photo": {"src": "https://example.com/hello.jpg", "w": 640},
+	"stories": [{"title": "First Story"}, {"title": "Second"}]
+}`)
+	want := json.RawMessage(`{"firstItemTitle":"First Story","headline":"Hello, World!","image":{"url":"https://example.com/hello.jpg","width":640},"items":[{"lowerTitle":"first story","title":"First Story"},{"lowerTitle":"second","title":"Second"}],"slug":"hello-world-","thumbnail":"https://example.com/hello.jpg?width=100"}`)
+
+	// The order of the fields would otherwise vary between runs so several are done
+	for i := 0; i < 10; i++ {
+		got, err := tr.Transform(in)
+		if err != nil {
+			t.Fatalf("got error, want nil: %v", err)
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Fatalf("got\n%s\nwant\n%s", got, want)
+		}
+	}
+}
+
+func TestOutputReferencesAcrossLevels(t *testing.T) {
+	schema, err := jsonschema.SchemaFromFile("./test_data/output-references-cross.json", "")
+	if err != nil {
+		t.Fatalf("failed to load schema: %v", err)
+	}
+	tr, err := NewTransformer(schema, "cumulo")
+	if err != nil {
+		t.Fatalf("failed to initialize transformer: %v", err)
+	}
+
+	want := `{"a":{"w":"one","x":"two"},"b":{"y":"two","z":"one"}}`
+	for i := 0; i < 10; i++ {
+		got, err := tr.Transform(json.RawMessage(`{"first": "one", "second": "two"}`))
+		if err != nil {
+			t.Fatalf("got error, want nil: %v", err)
+		}
+		if string(got) != want {
+			t.Fatalf("got\n%s\nwant\n%s", got, want)
+		}
+	}
+}
+
+// Array indexes in `$out.` paths are those of the input items, so they still select the same item when earlier
+// items are left out of the output.
+func TestOutputReferencesInputIndex(t *testing.T) {
+	schema, err := jsonschema.SchemaFromFile("./test_data/output-references.json", "")
+	if err != nil {
+		t.Fatalf("failed to load schema: %v", err)
+	}
+	tr, err := NewTransformer(schema, "cumulo")
+	if err != nil {
+		t.Fatalf("failed to initialize transformer: %v", err)
+	}
+
+	got, err := tr.Transform(json.RawMessage(`{"title": "Hello", "stories": [{}, {"title": "Second"}]}`))
+	if err != nil {
+		t.Fatalf("got error, want nil: %v", err)
+	}
+	want := `{"headline":"Hello","items":[{"lowerTitle":"second","title":"Second"}],"slug":"hello"}`
+	if string(got) != want {
+		t.Errorf("got\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestOutputReferencesInvalid(t *testing.T) {
+	tests := []struct {
+		description string
+		schemaPath  string
+	}{
+		{
+			description: "circular references",
+			schemaPath:  "./test_data/output-references-circular.json",
+		},
+		{
+			description: "circular references through the children of a referenced object",
+			schemaPath:  "./test_data/output-references-circular-child.json",
+		},
+		{
+			description: "reference to a field not in the schema",
+			schemaPath:  "./test_data/output-references-missing.json",
+		},
+		{
+			description: "reference to a child from the parent",
+			schemaPath:  "./test_data/output-references-parent.json",
+		},
+		{
+			description: "wildcard outside of the array",
+			schemaPath:  "./test_data/output-references-wildcard.json",
+		},
+	}
+
+	for _, test := range tests {
+		schema, err := jsonschema.SchemaFromFile(test.schemaPath, "")
+		if err != nil {
+			t.Fatalf("Test %q - failed to load schema: %v", test.description, err)
+		}
+		if _, err := NewTransformer(schema, "cumulo"); err == nil {
+			t.Errorf("Test %q - got nil, want error", test.description)
+		}
+	}
+}
+
+func TestSplitCommonPath(t *testing.T) {
+	tests := []struct {
+		description string
+		a           string
+		b           string
+		want        []string
+	}{
+		{
+			description: "siblings",
+			a:           "$.slug",
+			b:           "$.headline",
+			want:        []string{"$", "slug", "headline"},
+		},
+		{
+			description: "within an array item",
+			a:           "$.items[*].lowerTitle",
+			b:           "$.items[*].title",
+			want:        []string{"$.items[*]", "lowerTitle", "title"},
+		},
+		{
+			description: "different depths",
+			a:           "$.thumbnail",
+			b:           "$.image.url",
+			want:        []string{"$", "thumbnail", "image"},
+		},
+		{
+			description: "parent",
+			a:           "$.image",
+			b:           "$.image.url",
+			want:        []string{"$.image", "", ""},
+		},
+		{
+			description: "same path",
+			a:           "$.items[*].title",
+			b:           "$.items[*].title",
+			want:        []string{"$.items[*].title", "", ""},
+		},
+	}
+
+	for _, test := range tests {
+		common, a, b := splitCommonPath(test.a, test.b)
+		if got := []string{common, a, b}; !reflect.DeepEqual(got, test.want) {
+			t.Errorf("Test %q - got %v, want %v", test.description, got, test.want)
+		}
+	}
+}
+
+func TestCheckCircularReferences(t *testing.T) {
+	instances := map[string]instanceTransformer{"a": nil, "b": nil, "c": nil, "d": nil}
+
+	tests := []struct {
+		description  string
+		dependencies map[string][]string
+		wantErr      bool
+	}{
+		{
+			description: "no dependencies",
+		},
+		{
+			description:  "chain",
+			dependencies: map[string][]string{"a": {"b"}, "b": {"d"}},
+		},
+		{
+			description:  "multiple dependencies",
+			dependencies: map[string][]string{"a": {"d", "c"}, "c": {"d"}},
+		},
+		{
+			description:  "circular",
+			dependencies: map[string][]string{"a": {"b"}, "b": {"c"}, "c": {"a"}},
+			wantErr:      true,
+		},
+		{
+			description:  "self reference",
+			dependencies: map[string][]string{"d": {"d"}},
+			wantErr:      true,
+		},
+	}
+
+	for _, test := range tests {
+		err := checkCircularReferences(instances, test.dependencies)
+		if got := err != nil; got != test.wantErr {
+			t.Errorf("Test %q - got error %v, want error %v", test.description, err, test.wantErr)
+		}
+	}
+}
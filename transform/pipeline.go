@@ -0,0 +1,159 @@
+package transform
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// PipelineStage is a step of a Pipeline, ie the migration of documents from one version of a schema to the next.
+type PipelineStage struct {
+	Version     string // Identifies the input of the stage, ie "v1" for the migration of v1 documents to v2
+	Transformer *Transformer
+}
+
+// PipelineArgs contains optional settings for a Pipeline.
+//
+//	ValidateLastOnly skips the validation of the output of each stage but the last against the schema of its
+//	Transformer.
+type PipelineArgs struct {
+	ValidateLastOnly bool
+}
+
+// PipelineError is returned when a stage of a Pipeline fails, identifying the stage.
+type PipelineError struct {
+	Stage   int    // The index of the stage
+	Version string // The Version of the stage
+	Err     error
+}
+
+func (e *PipelineError) Error() string {
+	return fmt.Sprintf("pipeline stage %d (%s) failed: %v", e.Stage, e.Version, e.Err)
+}
+
+func (e *PipelineError) Unwrap() error {
+	return e.Err
+}
+
+// Pipeline chains Transformers, the output of each stage is the input of the next. Each stage gets the same input as a
+// Transform of the output of the previous stage, the output is passed on as decoded JSON rather than as raw bytes.
+// This allows stored documents to be migrated through several versions of a schema, starting from whichever version
+// they are at.
+type Pipeline struct {
+	stages []PipelineStage
+	args   PipelineArgs
+}
+
+// NewPipeline returns a Pipeline running the stages in order. Each stage must have a unique Version and a
+// Transformer for JSON input.
+func NewPipeline(stages []PipelineStage, args PipelineArgs) (*Pipeline, error) {
+	if len(stages) == 0 {
+		return nil, errors.New("a pipeline needs at least one stage")
+	}
+
+	versions := make(map[string]bool, len(stages))
+	for i, stage := range stages {
+		if stage.Version == "" {
+			return nil, fmt.Errorf("stage %d has no version", i)
+		}
+		if versions[stage.Version] {
+			return nil, fmt.Errorf("duplicate stage version %q", stage.Version)
+		}
+		versions[stage.Version] = true
+
+		if stage.Transformer == nil {
+			return nil, fmt.Errorf("stage %q has no Transformer", stage.Version)
+		}
+		if stage.Transformer.format != jsonInput {
+			return nil, fmt.Errorf("stage %q must have a Transformer for JSON input", stage.Version)
+		}
+	}
+
+	return &Pipeline{stages: stages, args: args}, nil
+}
+
+// Transform runs the input through every stage of the Pipeline. The output of the last stage is always validated,
+// an error from a stage is a *PipelineError.
+func (p *Pipeline) Transform(raw json.RawMessage) (json.RawMessage, error) {
+	return p.TransformContext(context.Background(), raw)
+}
+
+// TransformContext is the same as Transform but the context and options are used for the call of every stage, as
+// with the TransformContext of a Transformer.
+func (p *Pipeline) TransformContext(ctx context.Context, raw json.RawMessage, opts ...CallOption) (json.RawMessage, error) {
+	return p.transform(ctx, 0, raw, opts)
+}
+
+// TransformFrom is the same as TransformContext but starts at the stage with the given Version, ie the version of the
+// input.
+func (p *Pipeline) TransformFrom(ctx context.Context, version string, raw json.RawMessage, opts ...CallOption) (json.RawMessage, error) {
+	for i, stage := range p.stages {
+		if stage.Version == version {
+			return p.transform(ctx, i, raw, opts)
+		}
+	}
+	return nil, fmt.Errorf("no pipeline stage for version %q", version)
+}
+
+// transform runs the input through the stages starting at the given index.
+func (p *Pipeline) transform(ctx context.Context, start int, raw json.RawMessage, opts []CallOption) (json.RawMessage, error) {
+	in, err := decodeJSON(raw)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse input JSON: %v", err)
+	}
+
+	last := len(p.stages) - 1
+	for i := start; i < last; i++ {
+		if in, err = p.runStage(ctx, opts, i, in); err != nil {
+			return nil, &PipelineError{Stage: i, Version: p.stages[i].Version, Err: err}
+		}
+	}
+
+	tr := p.stages[last].Transformer
+	call, err := tr.newCall(ctx, opts)
+	var transformed json.RawMessage
+	if err == nil {
+		transformed, err = tr.transformDecoded(call, in)
+	}
+	if err == nil {
+		transformed, err = tr.encodeOutput(tr.validateJSON(transformed))
+	}
+	if err != nil {
+		return nil, &PipelineError{Stage: last, Version: p.stages[last].Version, Err: err}
+	}
+	return transformed, nil
+}
+
+// runStage runs an intermediate stage returning its output decoded as JSON input is, so values such as a time.Time
+// are the strings the next stage would read from JSON. The output is validated unless ValidateLastOnly is set.
+func (p *Pipeline) runStage(ctx context.Context, opts []CallOption, i int, in interface{}) (interface{}, error) {
+	tr := p.stages[i].Transformer
+	call, err := tr.newCall(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	if call.inputs != nil {
+		call.inputs[PrimaryInput] = in
+	}
+	transformed, err := tr.root.transform(call, in, nil)
+	if err != nil && err != errNullValue {
+		return nil, fmt.Errorf("failed transformation: %v", err)
+	}
+
+	out, err := json.Marshal(transformed)
+	if err != nil {
+		return nil, fmt.Errorf("failed to JSON marshal transformed data: %v", err)
+	}
+	if !p.args.ValidateLastOnly {
+		if _, err := tr.validateJSON(out); err != nil {
+			return nil, err
+		}
+	}
+
+	next, err := decodeJSON(out)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse transformed data: %v", err)
+	}
+	return next, nil
+}
@@ -0,0 +1,225 @@
+package transform
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/GannettDigital/jstransform/jsonschema"
+)
+
+func newTestPipeline(t *testing.T, args PipelineArgs) *Pipeline {
+	var stages []PipelineStage
+	for _, stage := range []struct{ version, schemaPath string }{
+		{"v1", "./test_data/pipeline/v2.json"},
+		{"v2", "./test_data/pipeline/v3.json"},
+	} {
+		schema, err := jsonschema.SchemaFromFile(stage.schemaPath, "")
+		if err != nil {
+			t.Fatalf("failed to load schema: %v", err)
+		}
+		tr, err := NewTransformer(schema, "cumulo")
+		if err != nil {
+			t.Fatalf("failed to initialize transformer: %v", err)
+		}
+		stages = append(stages, PipelineStage{Version: stage.version, Transformer: tr})
+	}
+
+	pipeline, err := NewPipeline(stages, args)
+	if err != nil {
+		t.Fatalf("failed to initialize pipeline: %v", err)
+	}
+	return pipeline
+}
+
+func TestPipeline(t *testing.T) {
+	tests := []struct {
+		description string
+		args        PipelineArgs
+		version     string
+		in          json.RawMessage
+		want        string
+		wantStage   int
+		wantErr     bool
+	}{
+		{
+			description: "all stages",
+			version:     "v1",
+			in:          json.RawMessage(`{"title": "Big News", "author": "Jane Doe", "words": 250}`),
+			want:        `{"credit":"JANE DOE","headline":"Big News","wordCount":250}`,
+		},
+		{
+			description: "from a later version",
+			version:     "v2",
+			in:          json.RawMessage(`{"headline": "Big News", "byline": "Jane Doe"}`),
+			want:        `{"credit":"JANE DOE","headline":"Big News"}`,
+		},
+		{
+			description: "invalid intermediate output",
+			version:     "v1",
+			in:          json.RawMessage(`{"title": "Big News"}`),
+			wantStage:   0,
+			wantErr:     true,
+		},
+		{
+			description: "intermediate output not validated",
+			args:        PipelineArgs{ValidateLastOnly: true},
+			version:     "v1",
+			in:          json.RawMessage(`{"title": "Big News"}`),
+			want:        `{"headline":"Big News"}`,
+		},
+		{
+			description: "invalid final output",
+			version:     "v1",
+			in:          json.RawMessage(`{"title": "Big News", "author": "Jane Doe", "words": 0}`),
+			wantStage:   1,
+			wantErr:     true,
+		},
+		{
+			description: "unknown version",
+			version:     "v3",
+			in:          json.RawMessage(`{"headline": "Big News"}`),
+			wantStage:   -1,
+			wantErr:     true,
+		},
+	}
+
+	for _, test := range tests {
+		pipeline := newTestPipeline(t, test.args)
+		got, err := pipeline.TransformFrom(context.Background(), test.version, test.in)
+		switch {
+		case test.wantErr && err != nil:
+			var pipelineErr *PipelineError
+			if !errors.As(err, &pipelineErr) {
+				if test.wantStage != -1 {
+					t.Errorf("Test %q - got error %v, want a PipelineError", test.description, err)
+				}
+				continue
+			}
+			if pipelineErr.Stage != test.wantStage {
+				t.Errorf("Test %q - got failed stage %d, want %d", test.description, pipelineErr.Stage, test.wantStage)
+			}
+			continue
+		case test.wantErr && err == nil:
+			t.Errorf("Test %q - got nil, want error", test.description)
+			continue
+		case !test.wantErr && err != nil:
+			t.Errorf("Test %q - got error, want nil: %v", test.description, err)
+			continue
+		}
+
+		if string(got) != test.want {
+			t.Errorf("Test %q - got\n%s\nwant\n%s", test.description, got, test.want)
+		}
+	}
+
+	got, err := newTestPipeline(t, PipelineArgs{}).Transform(json.RawMessage(`{"title": "Big News", "author": "Jane Doe"}`))
+	if err != nil {
+		t.Fatalf("got error, want nil: %v", err)
+	}
+	if want := `{"credit":"JANE DOE","headline":"Big News"}`; string(got) != want {
+		t.Errorf("got\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestPipelineStageInput(t *testing.T) {
+	var transformers []*Transformer
+	for _, schemaPath := range []string{"./test_data/pipeline/dates-a.json", "./test_data/pipeline/dates-b.json"} {
+		schema, err := jsonschema.SchemaFromFile(schemaPath, "")
+		if err != nil {
+			t.Fatalf("failed to load schema: %v", err)
+		}
+		tr, err := NewTransformer(schema, "cumulo")
+		if err != nil {
+			t.Fatalf("failed to initialize transformer: %v", err)
+		}
+		transformers = append(transformers, tr)
+	}
+	in := json.RawMessage(`{"date": "2024-05-01T10:00:00Z", "rating": 4.5}`)
+
+	// Each stage gets the same input as a Transform of the output of the previous stage.
+	want, err := transformers[0].Transform(in)
+	if err != nil {
+		t.Fatalf("got error from the first Transformer, want nil: %v", err)
+	}
+	if want, err = transformers[1].Transform(want); err != nil {
+		t.Fatalf("got error from the second Transformer, want nil: %v", err)
+	}
+
+	for _, validateLastOnly := range []bool{false, true} {
+		pipeline, err := NewPipeline([]PipelineStage{
+			{Version: "a", Transformer: transformers[0]},
+			{Version: "b", Transformer: transformers[1]},
+		}, PipelineArgs{ValidateLastOnly: validateLastOnly})
+		if err != nil {
+			t.Fatalf("failed to initialize pipeline: %v", err)
+		}
+
+		got, err := pipeline.Transform(in)
+		if err != nil {
+			t.Errorf("ValidateLastOnly %t - got error, want nil: %v", validateLastOnly, err)
+			continue
+		}
+		if string(got) != string(want) {
+			t.Errorf("ValidateLastOnly %t - got\n%s\nwant\n%s", validateLastOnly, got, want)
+		}
+	}
+}
+
+func TestNewPipeline(t *testing.T) {
+	schema, err := jsonschema.SchemaFromFile("./test_data/pipeline/v3.json", "")
+	if err != nil {
+		t.Fatalf("failed to load schema: %v", err)
+	}
+	tr, err := NewTransformer(schema, "cumulo")
+	if err != nil {
+		t.Fatalf("failed to initialize transformer: %v", err)
+	}
+	xmlTr, err := NewXMLTransformer(schema, "cumulo")
+	if err != nil {
+		t.Fatalf("failed to initialize transformer: %v", err)
+	}
+
+	tests := []struct {
+		description string
+		stages      []PipelineStage
+		wantErr     bool
+	}{
+		{
+			description: "valid",
+			stages:      []PipelineStage{{Version: "v1", Transformer: tr}, {Version: "v2", Transformer: tr}},
+		},
+		{
+			description: "no stages",
+			wantErr:     true,
+		},
+		{
+			description: "no version",
+			stages:      []PipelineStage{{Transformer: tr}},
+			wantErr:     true,
+		},
+		{
+			description: "duplicate version",
+			stages:      []PipelineStage{{Version: "v1", Transformer: tr}, {Version: "v1", Transformer: tr}},
+			wantErr:     true,
+		},
+		{
+			description: "no transformer",
+			stages:      []PipelineStage{{Version: "v1"}},
+			wantErr:     true,
+		},
+		{
+			description: "XML transformer",
+			stages:      []PipelineStage{{Version: "v1", Transformer: xmlTr}},
+			wantErr:     true,
+		},
+	}
+
+	for _, test := range tests {
+		_, err := NewPipeline(test.stages, PipelineArgs{})
+		if test.wantErr != (err != nil) {
+			t.Errorf("Test %q - got error %v, want error %t", test.description, err, test.wantErr)
+		}
+	}
+}
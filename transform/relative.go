@@ -0,0 +1,101 @@
+package transform
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+const (
+	// indexSelector is the path selecting the index of the current array item, with a `^` prefix for each level up.
+	indexSelector = "$index"
+	// parentSelector is the long form of the `^` selector, selecting the object one level above the `@` selector.
+	parentSelector = "@parent"
+)
+
+// resolveRelativePaths replaces the `^` and `@parent` selectors at the start of the jsonPaths of the instructions and
+// prepares the instructions using the `$index` selector. The parentPath is the path the `@` selector refers to.
+func (tis *transformInstructions) resolveRelativePaths(parentPath string) error {
+	for _, instruction := range tis.From {
+		if err := instruction.resolveRelativePaths(parentPath); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (ti *transformInstruction) resolveRelativePaths(parentPath string) error {
+	for _, path := range []string{ti.jsonPath, ti.xmlPath} {
+		levels, rest := parentLevels(path)
+		if rest != indexSelector {
+			continue
+		}
+		ancestor, err := ancestorPath(parentPath, levels)
+		if err != nil {
+			return fmt.Errorf("invalid selector %q: %v", path, err)
+		}
+		end := strings.LastIndex(ancestor, "[*]")
+		if end == -1 {
+			return fmt.Errorf("invalid selector %q: %q is not within an array", path, ancestor)
+		}
+		ti.indexPath = ancestor[:end+len("[*]")]
+		ti.jsonPath = ""
+		ti.xmlPath = ""
+		return nil
+	}
+
+	levels, rest := parentLevels(ti.jsonPath)
+	if levels == 0 {
+		return nil
+	}
+	if rest != "" && !strings.HasPrefix(rest, ".") && !strings.HasPrefix(rest, "[") {
+		return fmt.Errorf("invalid relative jsonPath %q", ti.jsonPath)
+	}
+	ancestor, err := ancestorPath(parentPath, levels)
+	if err != nil {
+		return fmt.Errorf("invalid relative jsonPath %q: %v", ti.jsonPath, err)
+	}
+	ti.jsonPath = ancestor + rest
+	return nil
+}
+
+// parentLevels returns the number of levels up selected at the start of the path, either by a `^` for each level or
+// by `@parent` for one, along with the rest of the path.
+func parentLevels(path string) (int, string) {
+	if strings.HasPrefix(path, parentSelector) {
+		return 1, strings.TrimPrefix(path, parentSelector)
+	}
+	rest := strings.TrimLeft(path, "^")
+	return len(path) - len(rest), rest
+}
+
+// ancestorPath returns the path the given number of levels above path. An array and its items are a single level so
+// one level above `$.articles[*].images[*]` is the article `$.articles[*]`.
+func ancestorPath(path string, levels int) (string, error) {
+	splits := strings.Split(path, ".")
+	if levels >= len(splits) {
+		return "", fmt.Errorf("%d levels above %q is beyond the root", levels, path)
+	}
+	return strings.Join(splits[:len(splits)-levels], "."), nil
+}
+
+// indexTransform returns the index of the array item selected by the `$index` selector, the index is found from the
+// modified path of the item. Outside of the array the path is unmodified and there is no value.
+func (ti *transformInstruction) indexTransform(call *transformCall, fieldType string, modifier pathModifier) (interface{}, error) {
+	path := applyModifier(modifier, ti.indexPath)
+	start := strings.LastIndex(path, "[")
+	index, err := strconv.ParseInt(path[start+1:len(path)-1], 10, 64)
+	if err != nil {
+		return nil, nil
+	}
+
+	value, err := convert(index, fieldType)
+	if err != nil {
+		value = index
+	}
+	value, err = call.runOperations(ti.Operations, value, path)
+	if err != nil && err != errNullValue {
+		return nil, fmt.Errorf("failed operation on the index of %q: %v", path, err)
+	}
+	return value, err
+}
@@ -0,0 +1,98 @@
+package transform
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestResolveRelativePaths(t *testing.T) {
+	tests := []struct {
+		description string
+		parentPath  string
+		instruction *transformInstruction
+		want        *transformInstruction
+		wantErr     bool
+	}{
+		{
+			description: "absolute path",
+			parentPath:  "$.articles[*]",
+			instruction: &transformInstruction{jsonPath: "$.id"},
+			want:        &transformInstruction{jsonPath: "$.id"},
+		},
+		{
+			description: "parent",
+			parentPath:  "$.articles[*].images[*]",
+			instruction: &transformInstruction{jsonPath: "^.id"},
+			want:        &transformInstruction{jsonPath: "$.articles[*].id"},
+		},
+		{
+			description: "parent long form",
+			parentPath:  "$.articles[*].images[*]",
+			instruction: &transformInstruction{jsonPath: "@parent['id']"},
+			want:        &transformInstruction{jsonPath: "$.articles[*]['id']"},
+		},
+		{
+			description: "whole parent",
+			parentPath:  "$.articles[*].images[*]",
+			instruction: &transformInstruction{jsonPath: "^"},
+			want:        &transformInstruction{jsonPath: "$.articles[*]"},
+		},
+		{
+			description: "multiple levels through an object",
+			parentPath:  "$.articles[*].media.images[*]",
+			instruction: &transformInstruction{jsonPath: "^^.id"},
+			want:        &transformInstruction{jsonPath: "$.articles[*].id"},
+		},
+		{
+			description: "root",
+			parentPath:  "$.articles[*]",
+			instruction: &transformInstruction{jsonPath: "^.siteCode"},
+			want:        &transformInstruction{jsonPath: "$.siteCode"},
+		},
+		{
+			description: "beyond the root",
+			parentPath:  "$.articles[*]",
+			instruction: &transformInstruction{jsonPath: "^^.siteCode"},
+			wantErr:     true,
+		},
+		{
+			description: "invalid relative path",
+			parentPath:  "$.articles[*]",
+			instruction: &transformInstruction{jsonPath: "^siteCode"},
+			wantErr:     true,
+		},
+		{
+			description: "index",
+			parentPath:  "$.articles[*].images[*]",
+			instruction: &transformInstruction{jsonPath: "$index"},
+			want:        &transformInstruction{indexPath: "$.articles[*].images[*]"},
+		},
+		{
+			description: "index of an outer array through an object",
+			parentPath:  "$.articles[*].media.images[*]",
+			instruction: &transformInstruction{xmlPath: "^$index"},
+			want:        &transformInstruction{indexPath: "$.articles[*]"},
+		},
+		{
+			description: "index outside an array",
+			parentPath:  "$.media",
+			instruction: &transformInstruction{jsonPath: "$index"},
+			wantErr:     true,
+		},
+	}
+
+	for _, test := range tests {
+		err := test.instruction.resolveRelativePaths(test.parentPath)
+
+		switch {
+		case test.wantErr && err != nil:
+			continue
+		case test.wantErr && err == nil:
+			t.Errorf("Test %q - got nil, want error", test.description)
+		case !test.wantErr && err != nil:
+			t.Errorf("Test %q - got error, want nil: %v", test.description, err)
+		case !reflect.DeepEqual(test.instruction, test.want):
+			t.Errorf("Test %q - got %+v, want %+v", test.description, test.instruction, test.want)
+		}
+	}
+}
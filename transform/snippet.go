@@ -0,0 +1,141 @@
+package transform
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"regexp"
+)
+
+const (
+	// refArgsKey is the key next to a `$ref` in a transform section or instruction holding the values for the
+	// placeholders of the referenced snippet.
+	refArgsKey = "refArgs"
+	// fromRefKey is added by the jsonschema package to the data resolved from a `$ref`.
+	fromRefKey = "fromRef"
+)
+
+// placeholderRe matches the `{{name}}` placeholders of a snippet.
+var placeholderRe = regexp.MustCompile(`\{\{\s*([\w-]+)\s*\}\}`)
+
+// wholePlaceholderRe matches a string which is only a placeholder.
+var wholePlaceholderRe = regexp.MustCompile(`^` + placeholderRe.String() + `$`)
+
+// substituteRefArgs replaces the placeholders within the transform section with the values of the refArgs in scope.
+// The `$ref` of a snippet is resolved when the schema is loaded, leaving the snippet in place along with the refArgs
+// and fromRef keys of the referencing object. Placeholders left without a value within a snippet are an error.
+func substituteRefArgs(raw json.RawMessage) (json.RawMessage, error) {
+	if !bytes.Contains(raw, []byte(`"`+refArgsKey+`"`)) && !bytes.Contains(raw, []byte(`"`+fromRefKey+`"`)) {
+		return raw, nil
+	}
+
+	section, err := decodeJSON(raw)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse the transform section: %v", err)
+	}
+	section, err = substitutePlaceholders(section, nil, false)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(section)
+}
+
+// substitutePlaceholders returns the value with the placeholders in its strings replaced by the args. Objects with
+// refArgs add to the args for their values, inSnippet is set within the data resolved from a `$ref`. The args of a
+// template operation use the same delimiters for Go template actions, see templateArgs.
+func substitutePlaceholders(value interface{}, args map[string]string, inSnippet bool) (interface{}, error) {
+	switch v := value.(type) {
+	case string:
+		return replacePlaceholders(v, args, inSnippet)
+	case []interface{}:
+		for i, item := range v {
+			var err error
+			if v[i], err = substitutePlaceholders(item, args, inSnippet); err != nil {
+				return nil, err
+			}
+		}
+		return v, nil
+	case map[string]interface{}:
+		if _, ok := v[fromRefKey]; ok {
+			inSnippet = true
+		}
+		if rawArgs, ok := v[refArgsKey]; ok {
+			var err error
+			if args, err = scopeRefArgs(rawArgs, args, inSnippet); err != nil {
+				return nil, err
+			}
+			delete(v, refArgsKey)
+		}
+		for key, item := range v {
+			if opArgs, ok := item.(map[string]interface{}); ok && key == "args" && v["type"] == "template" {
+				templateArgs(opArgs, args)
+				continue
+			}
+			var err error
+			if v[key], err = substitutePlaceholders(item, args, inSnippet); err != nil {
+				return nil, err
+			}
+		}
+		return v, nil
+	}
+	return value, nil
+}
+
+// templateArgs substitutes the args of a template operation which are a whole placeholder with a value, ie
+// `"template": "{{tmpl}}"`. Other values are left as they are because `{{end}}` or `{{else}}` are template actions.
+func templateArgs(opArgs map[string]interface{}, args map[string]string) {
+	for key, item := range opArgs {
+		text, ok := item.(string)
+		if !ok {
+			continue
+		}
+		if match := wholePlaceholderRe.FindStringSubmatch(text); match != nil {
+			if value, ok := args[match[1]]; ok {
+				opArgs[key] = value
+			}
+		}
+	}
+}
+
+// scopeRefArgs returns the args of the enclosing scope with the refArgs added. The values of the refArgs may use the
+// placeholders of the enclosing scope.
+func scopeRefArgs(rawArgs interface{}, args map[string]string, inSnippet bool) (map[string]string, error) {
+	refArgs, ok := rawArgs.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("%s must be an object not %T", refArgsKey, rawArgs)
+	}
+
+	scoped := make(map[string]string, len(args)+len(refArgs))
+	for name, value := range args {
+		scoped[name] = value
+	}
+	for name, rawValue := range refArgs {
+		value, ok := rawValue.(string)
+		if !ok {
+			return nil, fmt.Errorf("the %s value %q must be a string not %T", refArgsKey, name, rawValue)
+		}
+		value, err := replacePlaceholders(value, args, inSnippet)
+		if err != nil {
+			return nil, err
+		}
+		scoped[name] = value
+	}
+	return scoped, nil
+}
+
+// replacePlaceholders replaces the placeholders in the string with the args, within a snippet a placeholder without
+// a value is an error.
+func replacePlaceholders(s string, args map[string]string, inSnippet bool) (string, error) {
+	var err error
+	replaced := placeholderRe.ReplaceAllStringFunc(s, func(placeholder string) string {
+		name := placeholderRe.FindStringSubmatch(placeholder)[1]
+		if value, ok := args[name]; ok {
+			return value
+		}
+		if inSnippet && err == nil {
+			err = fmt.Errorf("no %s value for the placeholder %q", refArgsKey, placeholder)
+		}
+		return placeholder
+	})
+	return replaced, err
+}
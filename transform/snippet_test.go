@@ -0,0 +1,56 @@
+package transform
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestSubstituteRefArgs(t *testing.T) {
+	tests := []struct {
+		description string
+		raw         json.RawMessage
+		want        json.RawMessage
+		wantErr     bool
+	}{
+		{
+			description: "no snippet",
+			raw:         json.RawMessage(`{"from": [{"jsonPath": "$.title", "operations": [{"type": "replace", "args": {"regex": "{{x}}", "new": ""}}]}]}`),
+			want:        json.RawMessage(`{"from": [{"jsonPath": "$.title", "operations": [{"type": "replace", "args": {"regex": "{{x}}", "new": ""}}]}]}`),
+		},
+		{
+			description: "section snippet",
+			raw:         json.RawMessage(`{"from": [{"jsonPath": "$.{{ field }}.{{field}}"}], "fromRef": "#/definitions/a", "refArgs": {"field": "title"}}`),
+			want:        json.RawMessage(`{"from":[{"jsonPath":"$.title.title"}],"fromRef":"#/definitions/a"}`),
+		},
+		{
+			description: "nested refArgs using the enclosing refArgs",
+			raw:         json.RawMessage(`{"from": [{"jsonPath": "{{path}}", "fromRef": "#/definitions/b", "refArgs": {"path": "$.{{base}}.url"}}], "refArgs": {"base": "image"}}`),
+			want:        json.RawMessage(`{"from":[{"fromRef":"#/definitions/b","jsonPath":"$.image.url"}]}`),
+		},
+		{
+			description: "missing value",
+			raw:         json.RawMessage(`{"from": [{"jsonPath": "{{path}}"}], "fromRef": "#/definitions/a"}`),
+			wantErr:     true,
+		},
+		{
+			description: "value which is not a string",
+			raw:         json.RawMessage(`{"from": [{"jsonPath": "{{path}}"}], "fromRef": "#/definitions/a", "refArgs": {"path": 1}}`),
+			wantErr:     true,
+		},
+	}
+
+	for _, test := range tests {
+		got, err := substituteRefArgs(test.raw)
+
+		switch {
+		case test.wantErr && err != nil:
+			continue
+		case test.wantErr && err == nil:
+			t.Errorf("Test %q - got nil, want error", test.description)
+		case !test.wantErr && err != nil:
+			t.Errorf("Test %q - got error, want nil: %v", test.description, err)
+		case string(got) != string(test.want):
+			t.Errorf("Test %q - got %s, want %s", test.description, got, test.want)
+		}
+	}
+}
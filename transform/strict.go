@@ -0,0 +1,105 @@
+package transform
+
+import (
+	"fmt"
+	"strings"
+)
+
+// checkRequired returns an error for the first required field missing from the value of the object when the call is
+// strict. Objects without any value are skipped, other than the root, as they are checked by the required fields of
+// their parent.
+func (ot *objectTransformer) checkRequired(call *transformCall, value map[string]interface{}, modifier pathModifier) error {
+	if call == nil || !call.strict || (!hasValue(value) && ot.jsonPath != "$") {
+		return nil
+	}
+
+	for _, name := range ot.required {
+		// an explicit null is a value for nullable fields
+		if _, ok := value[name]; ok {
+			continue
+		}
+
+		path := ot.jsonPath + "." + name
+		child := ot.children[name]
+		if child == nil {
+			return fmt.Errorf("required field %q has no value, it is not in the properties of the schema", applyModifier(modifier, path))
+		}
+
+		tried := "nothing was tried"
+		if sources := instanceSources(child, modifier); len(sources) != 0 {
+			tried = "tried " + strings.Join(sources, ", ")
+		}
+		defaultStatus := "there is no default"
+		if instanceHasDefault(child) {
+			defaultStatus = "the default was not used"
+		}
+		return fmt.Errorf("required field %q has no value, %s and %s", applyModifier(modifier, path), tried, defaultStatus)
+	}
+	return nil
+}
+
+// instanceSources describes the input paths used to find the value of the instance.
+func instanceSources(it instanceTransformer, modifier pathModifier) []string {
+	if recorder, ok := it.(*outputRecorder); ok {
+		it = recorder.instanceTransformer
+	}
+
+	var sources []string
+	if tis := instanceTransforms(it); tis != nil {
+		for _, from := range tis.From {
+			switch {
+			case from.fromOutput:
+				sources = append(sources, fmt.Sprintf("jsonPath %q", "$out"+strings.TrimPrefix(applyModifier(modifier, from.jsonPath), "$")))
+			case from.fromVars:
+				sources = append(sources, fmt.Sprintf("jsonPath %q", "$vars"+strings.TrimPrefix(from.jsonPath, "$")))
+			case from.fromInputs:
+				sources = append(sources, fmt.Sprintf("jsonPath %q", "$inputs"+strings.TrimPrefix(from.jsonPath, "$")))
+			case from.jsonPath != "":
+				sources = append(sources, fmt.Sprintf("jsonPath %q", applyModifier(modifier, from.jsonPath)))
+			case from.xmlPath != "":
+				sources = append(sources, fmt.Sprintf("xmlPath %q", from.xmlPath))
+			}
+		}
+	}
+
+	var xmlFallback *transformInstruction
+	switch t := it.(type) {
+	case *arrayTransformer:
+		if t.format == jsonInput {
+			sources = append(sources, fmt.Sprintf("jsonPath %q", applyModifier(modifier, t.jsonPath)))
+		}
+		xmlFallback = t.xmlFallback
+	case *scalarTransformer:
+		if t.format == jsonInput {
+			sources = append(sources, fmt.Sprintf("jsonPath %q", applyModifier(modifier, t.jsonPath)))
+		}
+		xmlFallback = t.xmlFallback
+	}
+	if xmlFallback != nil {
+		sources = append(sources, fmt.Sprintf("xmlPath %q", xmlFallback.xmlPath))
+	}
+	return sources
+}
+
+// instanceHasDefault reports if the schema of the instance has a default value.
+func instanceHasDefault(it instanceTransformer) bool {
+	switch t := it.(type) {
+	case *outputRecorder:
+		return instanceHasDefault(t.instanceTransformer)
+	case *arrayTransformer:
+		return t.defaultValue != nil
+	case *objectTransformer:
+		return t.defaultValue != nil
+	case *scalarTransformer:
+		return t.defaultValue != nil
+	}
+	return false
+}
+
+// applyModifier returns the path changed by the modifier if there is one.
+func applyModifier(modifier pathModifier, path string) string {
+	if modifier == nil {
+		return path
+	}
+	return modifier(path)
+}
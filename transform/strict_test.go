@@ -0,0 +1,69 @@
+package transform
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/GannettDigital/jstransform/jsonschema"
+)
+
+func TestStrict(t *testing.T) {
+	schema, err := jsonschema.SchemaFromFile("./test_data/strict.json", "")
+	if err != nil {
+		t.Fatalf("failed to load schema: %v", err)
+	}
+	tr, err := NewTransformerWithArgs(schema, "cumulo", TransformerArgs{Strict: true})
+	if err != nil {
+		t.Fatalf("failed to initialize transformer: %v", err)
+	}
+
+	tests := []struct {
+		description string
+		in          json.RawMessage
+		want        json.RawMessage
+		wantErr     string
+	}{
+		{
+			description: "required fields present",
+			in:          json.RawMessage(`{"title": "Title", "byline": {"name": "Jane"}, "images": [{"src": "a.jpg"}]}`),
+			want:        json.RawMessage(`{"byline":{"name":"Jane"},"headline":"Title","images":[{"url":"a.jpg"}]}`),
+		},
+		{
+			description: "optional object missing",
+			in:          json.RawMessage(`{"title": "Title"}`),
+			want:        json.RawMessage(`{"headline":"Title"}`),
+		},
+		{
+			description: "missing root field",
+			in:          json.RawMessage(`{"byline": {"name": "Jane"}}`),
+			wantErr:     `required field "$.headline" has no value, tried jsonPath "$.seoTitle", jsonPath "$.title", jsonPath "$.headline" and there is no default`,
+		},
+		{
+			description: "missing field of an object",
+			in:          json.RawMessage(`{"title": "Title", "byline": {"email": "jane@example.com"}}`),
+			wantErr:     `required field "$.byline.name" has no value, tried jsonPath "$.byline.name" and there is no default`,
+		},
+		{
+			description: "missing field of an array item",
+			in:          json.RawMessage(`{"title": "Title", "images": [{"src": "a.jpg"}, {"caption": "No source"}]}`),
+			wantErr:     `required field "$.images[1].url" has no value, tried jsonPath "$.images[1].src", jsonPath "$.images[1].url" and there is no default`,
+		},
+	}
+
+	for _, test := range tests {
+		got, err := tr.Transform(test.in)
+
+		switch {
+		case test.wantErr != "" && err == nil:
+			t.Errorf("Test %q - got nil, want error", test.description)
+		case test.wantErr != "" && !strings.Contains(err.Error(), test.wantErr):
+			t.Errorf("Test %q - got error\n%v\nwant it to contain\n%s", test.description, err, test.wantErr)
+		case test.wantErr == "" && err != nil:
+			t.Errorf("Test %q - got error, want nil: %v", test.description, err)
+		case test.wantErr == "" && !reflect.DeepEqual(got, test.want):
+			t.Errorf("Test %q - got\n%s\nwant\n%s", test.description, got, test.want)
+		}
+	}
+}
@@ -7,6 +7,7 @@ import (
 	"strings"
 
 	jsonpath "github.com/GannettDigital/PaesslerAG_jsonpath"
+	"github.com/GannettDigital/jstransform/jsonschema"
 	"github.com/antchfx/xmlquery"
 )
 
@@ -16,6 +17,8 @@ const (
 	first transformMethod = iota
 	last
 	concatenate
+	firstValid
+	merge
 )
 
 // transformOperation defines the interface for operations that are implemented
@@ -25,27 +28,57 @@ type transformOperation interface {
 	transform(in interface{}) (interface{}, error)
 }
 
+// schemaOperation is implemented by transformOperations which also use details from the schema of the field they are
+// applied to. The initSchema function is called after init with the raw JSON schema of the field.
+type schemaOperation interface {
+	initSchema(schema json.RawMessage) error
+}
+
 type transformOperationJSON struct {
-	Name string            `json:"type"`
-	Args map[string]string `json:"args"`
+	Name    string            `json:"type"`
+	Args    map[string]string `json:"args"`
+	OnError string            `json:"onError"`
 }
 
 // transformInstruction defines a jsonPath and xmlPath for a transform and an
 // optional set of operations to be performed on the data from that path.
+// A jsonPath starting with `$out.` reads from the output of the transform rather than the input, in that case the
+// jsonPath is stored with the `$.` prefix and outputTarget is the part of it identifying the referenced instance.
+// Likewise a jsonPath starting with `$vars.` reads from the variables passed to the Transform call and one starting
+// with `$inputs.` from the named inputs passed to TransformMulti.
+// The xmlValue selects what is read from the nodes found with the xmlPath. The indexPath is set for the `$index`
+// selector, it is the path of the array item whose index is the value.
 type transformInstruction struct {
 	// For jsonPath format see http://goessner.net/articles/JsonPath/
 	jsonPath string
 	// For XPath format see https://devhints.io/xpath
-	xmlPath    string
-	Operations []transformOperation `json:"operations"`
+	xmlPath      string
+	xmlValue     xmlValue
+	fromOutput   bool
+	outputTarget string
+	outputUnit   *outputRecorder // Transformed on demand when the output target has not been transformed yet
+	fromVars     bool
+	fromInputs   bool
+	indexPath    string
+	Operations   []transformOperation `json:"operations"`
 }
 
 type transformInstructionJSON struct {
 	JSONPath   string                   `json:"jsonPath"`
 	XMLPath    string                   `json:"xmlPath"`
+	XMLValue   xmlValue                 `json:"xmlValue"`
 	Operations []transformOperationJSON `json:"operations"`
 }
 
+// xmlValue is the value read from XML nodes.
+type xmlValue string
+
+const (
+	xmlText       = xmlValue("")           // The concatenated text of the node and its children, the default
+	xmlInner      = xmlValue("innerXML")   // The raw XML within the node with the content of CDATA sections unwrapped
+	xmlAttributes = xmlValue("attributes") // An object of the attribute names and values of the node
+)
+
 // UnmarshalJSON implements the json.Unmarshaler interface, this function exists
 // to properly map the transformOperation.
 func (ti *transformInstruction) UnmarshalJSON(data []byte) error {
@@ -57,52 +90,147 @@ func (ti *transformInstruction) UnmarshalJSON(data []byte) error {
 
 	ti.jsonPath = jti.JSONPath
 	ti.xmlPath = jti.XMLPath
-	ti.Operations = []transformOperation{}
-
-	for _, toj := range jti.Operations {
-		var op transformOperation
-		switch toj.Name {
-		case "changeCase":
-			op = &changeCase{}
-		case "currentTime":
-			op = &currentTime{}
-		case "duration":
-			op = &duration{}
-		case "inverse":
-			op = &inverse{}
-		case "max":
-			op = &max{}
-		case "replace":
-			op = &replace{}
-		case "split":
-			op = &split{}
-		case "timeParse":
-			op = &timeParse{}
-		case "toCamelCase":
-			op = &toCamelCase{}
-		case "removeHTML":
-			op = &removeHTML{}
-		case "convertToFloat64":
-			op = &convertToFloat64{}
-		case "convertToInt64":
-			op = &convertToInt64{}
-		case "convertToBool":
-			op = &convertToBool{}
-		case "valueExists":
-			op = &valueExists{}
-		default:
-			return fmt.Errorf("unsupported operation %q", toj.Name)
+	switch jti.XMLValue {
+	case "text":
+		ti.xmlValue = xmlText
+	case xmlText, xmlInner, xmlAttributes:
+		ti.xmlValue = jti.XMLValue
+	default:
+		return fmt.Errorf("unknown xmlValue %q, must be one of 'text', 'innerXML' or 'attributes'", jti.XMLValue)
+	}
+	if strings.HasPrefix(ti.jsonPath, outputPrefix) {
+		ti.fromOutput = true
+		ti.jsonPath = "$" + strings.TrimPrefix(ti.jsonPath, "$out")
+	}
+	if strings.HasPrefix(ti.jsonPath, varsPrefix) {
+		ti.fromVars = true
+		ti.jsonPath = "$" + strings.TrimPrefix(ti.jsonPath, "$vars")
+	}
+	if strings.HasPrefix(ti.jsonPath, inputsPrefix) {
+		ti.fromInputs = true
+		ti.jsonPath = "$" + strings.TrimPrefix(ti.jsonPath, "$inputs")
+	}
+
+	var err error
+	ti.Operations, err = newOperations(jti.Operations)
+	return err
+}
+
+// newOperations builds and initializes the transformOperation for each of the given operations. Operations with
+// arguments read from the variables of the call are validated now and initialized when they are run.
+func newOperations(jops []transformOperationJSON) ([]transformOperation, error) {
+	operations := []transformOperation{}
+	for _, toj := range jops {
+		op, err := newOperation(toj.Name)
+		if err != nil {
+			return nil, err
 		}
 
-		if err := op.init(toj.Args); err != nil {
-			return fmt.Errorf("failed initializing transform operation: %v", err)
+		if hasVarsArgs(toj.Args) {
+			if op, err = newVarsOperation(toj.Name, toj.Args); err != nil {
+				return nil, err
+			}
+		} else if err := op.init(toj.Args); err != nil {
+			return nil, fmt.Errorf("failed initializing transform operation: %v", err)
 		}
-		ti.Operations = append(ti.Operations, op)
+		op, err = newOnErrorOperation(op, toj.Name, toj.OnError)
+		if err != nil {
+			return nil, err
+		}
+		operations = append(operations, op)
 	}
-	return nil
+	return operations, nil
 }
 
-func (ti *transformInstruction) xmlTransform(in interface{}, fieldType string, modifier pathModifier) (interface{}, error) {
+// newOperation returns the uninitialized transformOperation of the given type.
+func newOperation(name string) (transformOperation, error) {
+	var op transformOperation
+	switch name {
+	case "changeCase":
+		op = &changeCase{}
+	case "currentTime":
+		op = &currentTime{}
+	case "duration":
+		op = &duration{}
+	case "inverse":
+		op = &inverse{}
+	case "max":
+		op = &max{}
+	case "replace":
+		op = &replace{}
+	case "split":
+		op = &split{}
+	case "timeParse":
+		op = &timeParse{}
+	case "toCamelCase":
+		op = &toCamelCase{}
+	case "removeHTML":
+		op = &removeHTML{}
+	case "sanitizeHTML":
+		op = &sanitizeHTML{}
+	case "htmlToText":
+		op = &htmlToText{}
+	case "htmlExtract":
+		op = &htmlExtract{}
+	case "convertToFloat64":
+		op = &convertToFloat64{}
+	case "convertToInt64":
+		op = &convertToInt64{}
+	case "convertToBool":
+		op = &convertToBool{}
+	case "valueExists":
+		op = &valueExists{}
+	case "multiply":
+		op = &multiply{}
+	case "divide":
+		op = &divide{}
+	case "add":
+		op = &add{}
+	case "round":
+		op = &round{}
+	case "clamp":
+		op = &clamp{}
+	case "convertUnit":
+		op = &convertUnit{}
+	case "trim":
+		op = &trim{}
+	case "collapseWhitespace":
+		op = &collapseWhitespace{}
+	case "truncate":
+		op = &truncate{}
+	case "prefix":
+		op = &prefix{}
+	case "suffix":
+		op = &suffix{}
+	case "pad":
+		op = &pad{}
+	case "template":
+		op = &template{}
+	case "urlEncode":
+		op = &urlEncode{}
+	case "urlDecode":
+		op = &urlDecode{}
+	case "base64Decode":
+		op = &base64Decode{}
+	case "jsonParse":
+		op = &jsonParse{}
+	case "urlParse":
+		op = &urlParse{}
+	case "hash":
+		op = &hash{}
+	case "uuidv5":
+		op = &uuidv5{}
+	case "resolve":
+		op = &resolve{}
+	case "join":
+		op = &join{}
+	default:
+		return nil, fmt.Errorf("unsupported operation %q", name)
+	}
+	return op, nil
+}
+
+func (ti *transformInstruction) xmlTransform(call *transformCall, in interface{}, fieldType string, modifier pathModifier) (interface{}, error) {
 	path := ti.xmlPath
 	if modifier != nil {
 		path = modifier(path)
@@ -113,37 +241,56 @@ func (ti *transformInstruction) xmlTransform(in interface{}, fieldType string, m
 		return nil, errors.New("Error converting input to *xmlquery.Node")
 	}
 
-	xmlNode := xmlquery.Find(node, path)
+	xmlNode, err := call.findXML(node, path)
+	if err != nil {
+		return nil, fmt.Errorf("invalid xmlPath %q: %v", path, err)
+	}
 	if xmlNode == nil {
 		return nil, nil
 	}
 
-	var (
-		value interface{}
-		err   error
-	)
+	var value interface{}
 
 	// num elements that have a child element
-	numElementsWithChild := len(xmlquery.Find(node, path+"[*]"))
+	withChild, err := call.findXML(node, path+"[*]")
+	if err != nil {
+		return nil, fmt.Errorf("invalid xmlPath %q: %v", path, err)
+	}
+	numElementsWithChild := len(withChild)
 
 	// num elements without child
-	numElementsWithoutChild := len(xmlquery.Find(node, path+"[not(*)]"))
+	withoutChild, err := call.findXML(node, path+"[not(*)]")
+	if err != nil {
+		return nil, fmt.Errorf("invalid xmlPath %q: %v", path, err)
+	}
+	numElementsWithoutChild := len(withoutChild)
 
+	switch {
+	case ti.xmlValue == xmlAttributes:
+		if len(xmlNode) == 1 && fieldType != "array" {
+			value = nodeAttributes(xmlNode[0])
+			break
+		}
+		values := make([]interface{}, len(xmlNode))
+		for i, node := range xmlNode {
+			values[i] = nodeAttributes(node)
+		}
+		value = values
 	// if only numElementsWithoutChild has results then the nodes are leaf nodes and can extract value
-	if numElementsWithChild == 0 && numElementsWithoutChild == 1 {
-		value, err = convert(xmlNode[0].InnerText(), fieldType)
-	} else {
+	case numElementsWithChild == 0 && numElementsWithoutChild == 1:
+		value, err = convert(ti.nodeText(xmlNode[0]), fieldType)
+	default:
 		switch fieldType {
 		case "array", "object":
 			value = xmlNode
 		case "string":
 			values := make([]string, len(xmlNode))
 			for i, node := range xmlNode {
-				values[i] = node.InnerText()
+				values[i] = ti.nodeText(node)
 			}
 			value = strings.Join(values, " ")
 		default:
-			value, err = convert(xmlNode[0].InnerText(), fieldType)
+			value, err = convert(ti.nodeText(xmlNode[0]), fieldType)
 			if err != nil {
 				value = xmlNode
 			}
@@ -154,23 +301,73 @@ func (ti *transformInstruction) xmlTransform(in interface{}, fieldType string, m
 		return nil, nil
 	}
 
-	for _, op := range ti.Operations {
-		value, err = op.transform(value)
-		if err != nil {
-			return nil, fmt.Errorf("failed operation on value from xmlPath %q: %v", path, err)
+	value, err = call.runOperations(ti.Operations, value, path)
+	if err != nil && err != errNullValue {
+		return nil, fmt.Errorf("failed operation on value from xmlPath %q: %v", path, err)
+	}
+	return value, err
+}
+
+// nodeText returns the text of the node, either the inner text or the inner XML depending on the xmlValue.
+func (ti *transformInstruction) nodeText(node *xmlquery.Node) string {
+	if ti.xmlValue != xmlInner {
+		return node.InnerText()
+	}
+
+	var inner strings.Builder
+	for child := node.FirstChild; child != nil; child = child.NextSibling {
+		if child.Type == xmlquery.CharDataNode {
+			inner.WriteString(child.Data)
+			continue
+		}
+		inner.WriteString(child.OutputXML(true))
+	}
+	return inner.String()
+}
+
+// nodeAttributes returns the attributes of the node as a map of the name to the value. Namespace declarations are
+// skipped and prefixed names keep their prefix, ie `xlink:href`.
+func nodeAttributes(node *xmlquery.Node) map[string]interface{} {
+	attributes := make(map[string]interface{}, len(node.Attr))
+	for _, attr := range node.Attr {
+		if attr.Name.Space == "xmlns" || (attr.Name.Space == "" && attr.Name.Local == "xmlns") {
+			continue
 		}
+		name := attr.Name.Local
+		if attr.Name.Space != "" {
+			name = attr.Name.Space + ":" + name
+		}
+		attributes[name] = attr.Value
 	}
-	return value, nil
+	return attributes
 }
 
-func (ti *transformInstruction) jsonTransform(in interface{}, fieldType string, modifier pathModifier) (interface{}, error) {
+func (ti *transformInstruction) jsonTransform(call *transformCall, in interface{}, fieldType string, modifier pathModifier) (interface{}, error) {
 	path := ti.jsonPath
-	if modifier != nil {
+	if modifier != nil && !ti.fromVars && !ti.fromInputs {
 		path = modifier(path)
 	}
-	rawValue, err := jsonpath.Get(path, in)
-	if err != nil {
-		return nil, nil
+	var rawValue interface{}
+	switch {
+	case ti.fromOutput:
+		target := ti.outputTarget
+		if modifier != nil {
+			target = modifier(target)
+		}
+		var err error
+		if rawValue, err = call.output(ti.outputUnit, in, target, path); err != nil {
+			return nil, err
+		}
+	case ti.fromVars:
+		rawValue = call.variable(path)
+	case ti.fromInputs:
+		rawValue = call.input(path)
+	default:
+		var err error
+		rawValue, err = jsonpath.Get(path, in)
+		if err != nil {
+			return nil, nil
+		}
 	}
 	if rawValue == nil {
 		return nil, nil
@@ -185,45 +382,55 @@ func (ti *transformInstruction) jsonTransform(in interface{}, fieldType string,
 		return nil, nil
 	}
 
-	for _, op := range ti.Operations {
-		value, err = op.transform(value)
-		if err != nil {
-			return nil, fmt.Errorf("failed operation on value from jsonPath %q: %v", path, err)
-		}
+	value, err = call.runOperations(ti.Operations, value, path)
+	if err != nil && err != errNullValue {
+		return nil, fmt.Errorf("failed operation on value from jsonPath %q: %v", path, err)
 	}
-	return value, nil
+	return value, err
 }
 
 // transform runs the instructions in this object returning the new transformed value or an error if unable to.
 // It handles the logic for finding the value to be transformed and chaining the Operations.
 // It will not error if the value is not found, rather it returns nil for the value.
 // If a conversion or operation fails an error is returned.
-func (ti *transformInstruction) transform(in interface{}, fieldType string, modifier pathModifier, format inputFormat) (interface{}, error) {
-	if format == xmlInput {
-		return ti.xmlTransform(in, fieldType, modifier)
+func (ti *transformInstruction) transform(call *transformCall, in interface{}, fieldType string, modifier pathModifier, format inputFormat) (interface{}, error) {
+	if ti.indexPath != "" {
+		return ti.indexTransform(call, fieldType, modifier)
+	}
+	if format == xmlInput && !ti.fromVars {
+		return ti.xmlTransform(call, in, fieldType, modifier)
 	}
-	if format == jsonInput {
-		return ti.jsonTransform(in, fieldType, modifier)
+	if format == jsonInput || ti.fromVars {
+		return ti.jsonTransform(call, in, fieldType, modifier)
 	}
 	return nil, errors.New("no path type specified for transform")
 }
 
 // trransformInstructions defines a set of instructions and a method for combining their results.
 // The default method is to take the first non-nil result.
+// The optional Operations are performed on the combined result.
 type transformInstructions struct {
 	From          []*transformInstruction `json:"from"`
 	Method        transformMethod         `json:"method"`
 	MethodOptions methodOptions           `json:"methodOptions"`
+	Operations    []transformOperation    `json:"operations"`
+
+	path        string               // The jsonPath of the field the instructions are for
+	validator   jsonschema.Validator // Validates the candidates of the firstValid method against the field schema
+	uniqueItems *uniqueArrays        // Set from the field schema, merged arrays marked unique are a union
 }
 
 type transformInstructionsJSON struct {
-	From          []*transformInstruction `json:"from"`
-	Method        string                  `json:"method"`
-	MethodOptions methodOptions           `json:"methodOptions"`
+	From          []*transformInstruction  `json:"from"`
+	Method        string                   `json:"method"`
+	MethodOptions methodOptions            `json:"methodOptions"`
+	Operations    []transformOperationJSON `json:"operations"`
 }
 
 type methodOptions struct {
 	ConcatenateDelimiter string `json:"concatenateDelimiter"`
+	MergePrecedence      string `json:"mergePrecedence"`
+	MergeArrays          string `json:"mergeArrays"`
 }
 
 // UnmarshalJSON implements the json.Unmarshaler interface, this function exists to properly map the method.
@@ -237,6 +444,14 @@ func (tis *transformInstructions) UnmarshalJSON(data []byte) error {
 	tis.From = jtis.From
 	tis.MethodOptions = jtis.MethodOptions
 
+	if len(jtis.Operations) > 0 {
+		var err error
+		tis.Operations, err = newOperations(jtis.Operations)
+		if err != nil {
+			return err
+		}
+	}
+
 	switch jtis.Method {
 	case "":
 		tis.Method = 0
@@ -246,17 +461,32 @@ func (tis *transformInstructions) UnmarshalJSON(data []byte) error {
 		tis.Method = last
 	case "concatenate":
 		tis.Method = concatenate
+	case "firstValid":
+		tis.Method = firstValid
+	case "merge":
+		tis.Method = merge
 	default:
 		return fmt.Errorf("unknown method %q", jtis.Method)
 	}
 
+	switch tis.MethodOptions.MergePrecedence {
+	case "", "first", "last":
+	default:
+		return fmt.Errorf("unknown mergePrecedence %q, must be 'first' or 'last'", tis.MethodOptions.MergePrecedence)
+	}
+	switch tis.MethodOptions.MergeArrays {
+	case "", "append", "union":
+	default:
+		return fmt.Errorf("unknown mergeArrays %q, must be 'append' or 'union'", tis.MethodOptions.MergeArrays)
+	}
+
 	return nil
 }
 
 // transform runs the instructions in this object returning the new transformed value or nil if none is found.
-// It handles the logic for concatenation, first or last methods.
-func (tis *transformInstructions) transform(in interface{}, fieldType string, modifier pathModifier, format inputFormat) (interface{}, error) {
-	var concatResult bool
+// It handles the logic for concatenation, first, firstValid, last or merge methods.
+func (tis *transformInstructions) transform(call *transformCall, in interface{}, fieldType string, modifier pathModifier, format inputFormat) (interface{}, error) {
+	var concatResult, mergeResult bool
 	switch tis.Method {
 	case last:
 		var newFrom []*transformInstruction
@@ -266,12 +496,24 @@ func (tis *transformInstructions) transform(in interface{}, fieldType string, mo
 		tis.From = newFrom
 	case concatenate:
 		concatResult = true
+	case merge:
+		mergeResult = true
+	}
+
+	field := tis.path
+	if modifier != nil {
+		field = modifier(field)
 	}
+	defer call.setOperationErrorField(call.operationErrorCount(), field)
 
 	var result interface{}
 
 	for _, from := range tis.From {
-		value, err := from.transform(in, fieldType, modifier, format)
+		value, err := from.transform(call, in, fieldType, modifier, format)
+		if err == errNullValue && (concatResult || mergeResult) {
+			// the other values are still combined when one is nulled
+			continue
+		}
 		if err != nil {
 			return nil, err
 		}
@@ -283,13 +525,108 @@ func (tis *transformInstructions) transform(in interface{}, fieldType string, mo
 			}
 			continue
 		}
-		if value != nil {
+		if mergeResult {
+			result, err = tis.merge(result, value, fieldType)
+			if err != nil {
+				return nil, fmt.Errorf("failed to merge values: %v", err)
+			}
+			continue
+		}
+		if value != nil && tis.valid(value) {
 			result = value
 			break
 		}
 	}
 
-	return result, nil
+	if result == nil {
+		return nil, nil
+	}
+	result, err := call.runOperations(tis.Operations, result, "")
+	if err != nil && err != errNullValue {
+		return nil, fmt.Errorf("failed operation on the combined value: %v", err)
+	}
+	return result, err
+}
+
+// valid reports if the value is valid for the field, only values of the firstValid method are checked.
+func (tis *transformInstructions) valid(value interface{}) bool {
+	if tis.Method != firstValid || tis.validator == nil {
+		return true
+	}
+	raw, err := json.Marshal(value)
+	if err != nil {
+		return false
+	}
+	valid, err := tis.validator.Validate(raw)
+	return err == nil && valid
+}
+
+// initSchema passes the raw JSON schema of the field to any operations which implement schemaOperation and prepares
+// the firstValid and merge methods.
+func (tis *transformInstructions) initSchema(schema json.RawMessage) error {
+	switch tis.Method {
+	case firstValid:
+		var err error
+		if tis.validator, err = jsonschema.NewInstanceValidator(schema); err != nil {
+			return fmt.Errorf("failed initializing the firstValid method validation: %v", err)
+		}
+	case merge:
+		fieldType, _, err := jsonschema.FieldType(schema)
+		if err != nil {
+			return fmt.Errorf("failed to extract instance type: %v", err)
+		}
+		if fieldType != "array" && fieldType != "object" {
+			return fmt.Errorf("the merge method is only supported for array and object fields not %s fields", fieldType)
+		}
+		if tis.uniqueItems, err = newUniqueArrays(schema); err != nil {
+			return fmt.Errorf("failed to extract uniqueItems: %v", err)
+		}
+	}
+
+	operations := append([]transformOperation{}, tis.Operations...)
+	for _, from := range tis.From {
+		operations = append(operations, from.Operations...)
+	}
+
+	for _, op := range operations {
+		sop, ok := op.(schemaOperation)
+		if !ok {
+			continue
+		}
+		if err := sop.initSchema(schema); err != nil {
+			return fmt.Errorf("failed initializing transform operation: %v", err)
+		}
+	}
+	return nil
+}
+
+// merge combines the value with the result of the previous instructions. Objects are merged deeply, for keys in both
+// the value from the first or last instruction is kept according to the mergePrecedence option. Arrays are appended
+// or, with the union mergeArrays option or uniqueItems in the schema of the array, only new items are added.
+func (tis *transformInstructions) merge(result, value interface{}, fieldType string) (interface{}, error) {
+	if value == nil {
+		return result, nil
+	}
+	switch v := value.(type) {
+	case []*xmlquery.Node:
+		items := make([]interface{}, len(v))
+		for i, node := range v {
+			items[i] = node
+		}
+		value = items
+	case *xmlquery.Node:
+		if fieldType != "array" {
+			return nil, errors.New("XML objects can not be merged")
+		}
+	}
+	if _, ok := value.([]interface{}); fieldType == "array" && !ok {
+		value = []interface{}{value}
+	}
+	if result == nil {
+		return value, nil
+	}
+
+	return mergeValues(result, value, tis.MethodOptions.MergePrecedence == "last", tis.MethodOptions.MergeArrays == "union", tis.uniqueItems), nil
 }
 
 // replaceJSONPathPrefix will switch old for new in the path of the transform instructions if the path starts with
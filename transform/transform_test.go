@@ -90,7 +90,7 @@ func TestTransformInstruction(t *testing.T) {
 	}
 
 	for _, test := range tests {
-		got, err := test.ti.transform(test.in, "string", nil, test.format)
+		got, err := test.ti.transform(nil, test.in, "string", nil, test.format)
 
 		switch {
 		case test.wantErr && err != nil:
@@ -256,6 +256,49 @@ func TestTransformInstructions(t *testing.T) {
 			in:     testRaw,
 			want:   nil,
 		},
+		{
+			description: "multiple instructions - method concat with operations on the result",
+			tis: transformInstructions{
+				From: []*transformInstruction{
+					{jsonPath: "$.group1.item1.itemA"},
+					{jsonPath: "$.group3[1]"},
+				},
+				Method: concatenate,
+				MethodOptions: methodOptions{
+					ConcatenateDelimiter: "/",
+				},
+				Operations: []transformOperation{&testOp{args: map[string]string{"out": "combined"}}},
+			},
+			format: jsonInput,
+			in:     testRaw,
+			want:   "combined",
+		},
+		{
+			description: "operations on the result are skipped when no value is found",
+			tis: transformInstructions{
+				From: []*transformInstruction{
+					{jsonPath: "$.group10"},
+				},
+				Method:     first,
+				Operations: []transformOperation{&testOp{fail: true}},
+			},
+			format: jsonInput,
+			in:     testRaw,
+			want:   nil,
+		},
+		{
+			description: "failed operation on the result",
+			tis: transformInstructions{
+				From: []*transformInstruction{
+					{jsonPath: "$.group1.item1.itemA"},
+				},
+				Method:     first,
+				Operations: []transformOperation{&testOp{fail: true}},
+			},
+			format:  jsonInput,
+			in:      testRaw,
+			wantErr: true,
+		},
 		{
 			description: "all paths are missing",
 			tis: transformInstructions{
@@ -312,7 +355,7 @@ func TestTransformInstructions(t *testing.T) {
 	}
 
 	for _, test := range tests {
-		got, err := test.tis.transform(test.in, "string", nil, test.format)
+		got, err := test.tis.transform(nil, test.in, "string", nil, test.format)
 
 		switch {
 		case test.wantErr && err != nil:
@@ -360,6 +403,263 @@ func TestTransformUnmarshal(t *testing.T) {
 			},
 			},
 		},
+		{
+			description: "XML transform with xmlValue",
+			value: []byte(`
+{
+	"cumulo": {
+		"from": [
+			{
+				"xmlPath": "body",
+				"xmlValue": "innerXML"
+			},
+			{
+				"xmlPath": "text",
+				"xmlValue": "text"
+			}
+		]
+	}
+}`,
+			),
+			want: transform{"cumulo": transformInstructions{
+				From: []*transformInstruction{
+					{xmlPath: "body", xmlValue: xmlInner, Operations: []transformOperation{}},
+					{xmlPath: "text", xmlValue: xmlText, Operations: []transformOperation{}},
+				},
+				Method: first,
+			},
+			},
+		},
+		{
+			description: "Unknown xmlValue",
+			value: []byte(`
+{
+	"cumulo": {
+		"from": [
+			{
+				"xmlPath": "body",
+				"xmlValue": "outerXML"
+			}
+		]
+	}
+}`,
+			),
+			wantErr: true,
+		},
+		{
+			description: "Merge method with options",
+			value: []byte(`
+{
+	"cumulo": {
+		"from": [
+			{
+				"jsonPath": "$.tags"
+			}
+		],
+		"method": "merge",
+		"methodOptions": {
+			"mergePrecedence": "last",
+			"mergeArrays": "union"
+		}
+	}
+}`,
+			),
+			want: transform{"cumulo": transformInstructions{
+				From: []*transformInstruction{
+					{jsonPath: "$.tags", Operations: []transformOperation{}},
+				},
+				Method:        merge,
+				MethodOptions: methodOptions{MergePrecedence: "last", MergeArrays: "union"},
+			},
+			},
+		},
+		{
+			description: "Unknown mergeArrays option",
+			value: []byte(`
+{
+	"cumulo": {
+		"from": [
+			{
+				"jsonPath": "$.tags"
+			}
+		],
+		"method": "merge",
+		"methodOptions": {
+			"mergeArrays": "zip"
+		}
+	}
+}`,
+			),
+			wantErr: true,
+		},
+		{
+			description: "Operation with onError",
+			value: []byte(`
+{
+	"cumulo": {
+		"from": [
+			{
+				"jsonPath": "$.duration",
+				"operations": [
+					{
+						"type": "duration",
+						"onError": "skip"
+					}
+				]
+			}
+		]
+	}
+}`,
+			),
+			want: transform{"cumulo": transformInstructions{
+				From: []*transformInstruction{
+					{jsonPath: "$.duration", Operations: []transformOperation{
+						&onErrorOperation{transformOperation: &duration{re: durationRe}, name: "duration", policy: onErrorSkip},
+					}},
+				},
+				Method: first,
+			},
+			},
+		},
+		{
+			description: "Unknown onError policy",
+			value: []byte(`
+{
+	"cumulo": {
+		"from": [
+			{
+				"jsonPath": "$.duration",
+				"operations": [
+					{
+						"type": "duration",
+						"onError": "retry"
+					}
+				]
+			}
+		]
+	}
+}`,
+			),
+			wantErr: true,
+		},
+		{
+			description: "Variables",
+			value: []byte(`
+{
+	"cumulo": {
+		"from": [
+			{
+				"jsonPath": "$vars.slug",
+				"operations": [
+					{
+						"type": "prefix",
+						"args": {
+							"value": "$vars.siteCode"
+						}
+					}
+				]
+			}
+		]
+	}
+}`,
+			),
+			want: transform{"cumulo": transformInstructions{
+				From: []*transformInstruction{
+					{jsonPath: "$.slug", fromVars: true, Operations: []transformOperation{
+						&varsOperation{name: "prefix", args: map[string]string{"value": "$vars.siteCode"}},
+					}},
+				},
+				Method: first,
+			},
+			},
+		},
+		{
+			description: "Variables, argument only checked when run",
+			value: []byte(`
+{
+	"cumulo": {
+		"from": [
+			{
+				"jsonPath": "$.slug",
+				"operations": [
+					{
+						"type": "changeCase",
+						"args": {
+							"to": "$vars.case"
+						}
+					}
+				]
+			}
+		]
+	}
+}`,
+			),
+			want: transform{"cumulo": transformInstructions{
+				From: []*transformInstruction{
+					{jsonPath: "$.slug", Operations: []transformOperation{
+						&varsOperation{name: "changeCase", args: map[string]string{"to": "$vars.case"}},
+					}},
+				},
+				Method: first,
+			},
+			},
+		},
+		{
+			description: "Variables, valid argument",
+			value: []byte(`
+{
+	"cumulo": {
+		"from": [
+			{
+				"jsonPath": "$.slug",
+				"operations": [
+					{
+						"type": "replace",
+						"args": {
+							"regex": "^-",
+							"new": "$vars.siteCode"
+						}
+					}
+				]
+			}
+		]
+	}
+}`,
+			),
+			want: transform{"cumulo": transformInstructions{
+				From: []*transformInstruction{
+					{jsonPath: "$.slug", Operations: []transformOperation{
+						&varsOperation{name: "replace", args: map[string]string{"regex": "^-", "new": "$vars.siteCode"}},
+					}},
+				},
+				Method: first,
+			},
+			},
+		},
+		{
+			description: "Variables, invalid argument",
+			value: []byte(`
+{
+	"cumulo": {
+		"from": [
+			{
+				"jsonPath": "$.slug",
+				"operations": [
+					{
+						"type": "replace",
+						"args": {
+							"regex": "(",
+							"new": "$vars.siteCode"
+						}
+					}
+				]
+			}
+		]
+	}
+}`,
+			),
+			wantErr: true,
+		},
 		{
 			description: "Basic transform, last method",
 			value: []byte(`
@@ -432,6 +732,52 @@ func TestTransformUnmarshal(t *testing.T) {
 			},
 			},
 		},
+		{
+			description: "Concatenate method with operations on the result",
+			value: []byte(`
+{
+	"cumulo": {
+		"from": [
+			{
+				"jsonPath": "$.site"
+			},
+			{
+				"jsonPath": "$.id"
+			}
+		],
+		"method": "concatenate",
+		"methodOptions": {
+			"concatenateDelimiter": "/"
+		},
+		"operations": [
+			{
+				"type": "uuidv5",
+				"args": {
+					"namespace": "url"
+				}
+			}
+		]
+	}
+}`,
+			),
+			want: transform{"cumulo": transformInstructions{
+				From: []*transformInstruction{
+					{jsonPath: "$.site", Operations: []transformOperation{}},
+					{jsonPath: "$.id", Operations: []transformOperation{}},
+				},
+				Method: concatenate,
+				MethodOptions: methodOptions{
+					ConcatenateDelimiter: "/",
+				},
+				Operations: []transformOperation{
+					&uuidv5{
+						args:      map[string]string{"namespace": "url"},
+						namespace: []byte{0x6b, 0xa7, 0xb8, 0x11, 0x9d, 0xad, 0x11, 0xd1, 0x80, 0xb4, 0x00, 0xc0, 0x4f, 0xd4, 0x30, 0xc8},
+					},
+				},
+			},
+			},
+		},
 		{
 			description: "Two options",
 			value: []byte(`
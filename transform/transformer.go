@@ -4,9 +4,11 @@ package transform
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"regexp"
 	"strconv"
 	"strings"
 
@@ -16,6 +18,9 @@ import (
 	"github.com/antchfx/xmlquery"
 )
 
+// xmlNameRe matches the XML element names usable in the paths of the xmlFallback.
+var xmlNameRe = regexp.MustCompile(`^[A-Za-z_][\w-]*$`)
+
 // inputFormat denotes the type of transform to perfrom, the options are 'JSON' or 'XML'.
 type inputFormat string
 
@@ -34,42 +39,155 @@ type JSONTransformer interface {
 // More details on the transform section of the schema are found at
 // https://github.com/GannettDigital/jstransform/blob/master/transform.adoc
 type Transformer struct {
-	schema              *jsonschema.Schema
-	transformIdentifier string // Used to select the proper transform Instructions
-	root                instanceTransformer
-	format              inputFormat
+	schema               *jsonschema.Schema
+	transformIdentifiers []string // Used to select the proper transform Instructions, followed by any fallback identifiers
+	root                 instanceTransformer
+	format               inputFormat
+	args                 TransformerArgs
+	xmlOutput            *xmlNode       // Set when the output is written as XML
+	propertyOrder        *propertyOrder // Set when the output keys are in the order of the schema properties
+	emitters             []*emitter     // Set when the schema has emit instructions for the transform identifier
+}
+
+// Resolver looks up values in an external system for the resolve operation, for example an author profile by ID.
+// The returned value should be made up of the types encoding/json uses when unmarshalling into an interface{}, nil
+// should be returned without an error when nothing is found for the key.
+//
+// Resolve is called at most once per key and Transform call, it may be called concurrently by separate Transform calls.
+type Resolver interface {
+	Resolve(ctx context.Context, key string) (interface{}, error)
+}
+
+// TransformerArgs contains optional settings for a Transformer.
+//
+//	Resolvers are used by the resolve operation, they are keyed by the name given in its 'resolver' argument.
+//
+//	FallbackIdentifiers are transform identifiers tried in order for fields without a transform section for the
+//	transformIdentifier, for example ["presentationv4", "default"]. This allows a consumer to only define the
+//	transforms which differ from those of another consumer.
+//
+//	XMLNamespaces binds the namespace prefixes used in the xmlPaths of this transform identifier to namespace URIs,
+//	for example {"media": "http://search.yahoo.com/mrss/"}. Elements then match by namespace no matter which prefix
+//	the document uses.
+//
+//	OutputFormat selects the encoding of the transformed data, JSONOutput by default. With XMLOutput the transformed
+//	data is validated against the schema and then written as XML named using the `xml` objects of the schema.
+//
+//	PreserveOrder writes the keys of JSON objects in the order the properties are declared in the schema rather than
+//	in alphabetical order. Keys not in the schema follow those which are, in alphabetical order.
+//
+//	Strict checks the required fields of each object as it is transformed. The transform fails at the first required
+//	field without a value with an error naming the field, the input paths tried and whether the schema has a default.
+//
+//	EmptyValues sets how fields without a value or with an empty value are written, fields override it with an
+//	`emptyValues` object in their schema.
+//
+//	Clock provides the current time to operations such as currentTime, the system clock by default. It can be
+//	overridden for a single TransformContext call with WithClock, FixedClock returns a Clock for a set time.
+//
+//	Mapping holds transform sections for this transform identifier kept outside of the schema, keyed by the jsonPath
+//	of the field. They override or merge with the transform sections in the schema, paths of the Mapping which are
+//	not in the schema are an error.
+type TransformerArgs struct {
+	Resolvers           map[string]Resolver
+	FallbackIdentifiers []string
+	XMLNamespaces       map[string]string
+	OutputFormat        OutputFormat
+	PreserveOrder       bool
+	Strict              bool
+	EmptyValues         EmptyValuePolicy
+	Clock               Clock
+	Mapping             *Mapping
 }
 
 // NewTransformer returns a Transformer using the schema given.
 // The transformIdentifier is used to select the appropriate transform section from the schema.
 // It expects the transforms to be performed on JSON data.
 func NewTransformer(schema *jsonschema.Schema, tranformIdentifier string) (*Transformer, error) {
-	return newTransformer(schema, tranformIdentifier, jsonInput)
+	return newTransformer(schema, tranformIdentifier, jsonInput, TransformerArgs{})
+}
+
+// NewTransformerWithArgs is the same as NewTransformer but also accepts optional settings for the Transformer.
+func NewTransformerWithArgs(schema *jsonschema.Schema, tranformIdentifier string, args TransformerArgs) (*Transformer, error) {
+	return newTransformer(schema, tranformIdentifier, jsonInput, args)
 }
 
 // NewXMLTransformer returns a Transformer using the schema given.
 // The transformIdentifier is used to select the appropriate transform section from the schema.
 // It expects the transforms to be performed on XML data.
 func NewXMLTransformer(schema *jsonschema.Schema, tranformIdentifier string) (*Transformer, error) {
-	return newTransformer(schema, tranformIdentifier, xmlInput)
+	return newTransformer(schema, tranformIdentifier, xmlInput, TransformerArgs{})
+}
+
+// NewXMLTransformerWithArgs is the same as NewXMLTransformer but also accepts optional settings for the Transformer.
+func NewXMLTransformerWithArgs(schema *jsonschema.Schema, tranformIdentifier string, args TransformerArgs) (*Transformer, error) {
+	return newTransformer(schema, tranformIdentifier, xmlInput, args)
 }
 
-func newTransformer(schema *jsonschema.Schema, tranformIdentifier string, format inputFormat) (*Transformer, error) {
-	tr := &Transformer{schema: schema, transformIdentifier: tranformIdentifier, format: format}
+func newTransformer(schema *jsonschema.Schema, tranformIdentifier string, format inputFormat, args TransformerArgs) (*Transformer, error) {
+	tr := &Transformer{
+		schema:               schema,
+		transformIdentifiers: append([]string{tranformIdentifier}, args.FallbackIdentifiers...),
+		format:               format,
+		args:                 args,
+	}
 	emptyJSON := []byte(`{}`)
 	var err error
 	if schema.Properties != nil {
-		tr.root, err = newObjectTransformer("$", tranformIdentifier, emptyJSON, format)
+		tr.root, err = newObjectTransformer("$", tr.transformIdentifiers, emptyJSON, format)
 	} else if schema.Items != nil {
-		tr.root, err = newArrayTransformer("$", tranformIdentifier, emptyJSON, format)
+		tr.root, err = newArrayTransformer("$", tr.transformIdentifiers, emptyJSON, format)
 	} else {
 		return nil, errors.New("no Properties nor Items found for schema")
 	}
 	if err != nil {
 		return nil, fmt.Errorf("failed initializing root transformer: %v", err)
 	}
+	if root, ok := tr.root.(*objectTransformer); ok {
+		root.required = schema.Required
+	}
 
-	if err := jsonschema.WalkRaw(schema, tr.walker); err != nil {
+	schemaPaths := make(map[string]bool)
+	if err := jsonschema.WalkRaw(schema, func(path string, value json.RawMessage) error {
+		schemaPaths[path] = true
+		if args.Mapping != nil {
+			if value, err = args.Mapping.apply(path, value, tr.transformIdentifiers); err != nil {
+				return err
+			}
+		}
+		return tr.walker(path, value)
+	}); err != nil {
+		return nil, err
+	}
+	if args.Mapping != nil {
+		if unknown := args.Mapping.unknownPaths(schemaPaths); len(unknown) > 0 {
+			return nil, fmt.Errorf("the mapping has transforms for paths not in the schema: %s", strings.Join(unknown, ", "))
+		}
+	}
+	if format == jsonInput {
+		if err := tr.linkOutputReferences(); err != nil {
+			return nil, err
+		}
+	}
+	if format == xmlInput {
+		linkXMLFallbacks(tr.root, "$")
+	}
+
+	switch args.OutputFormat {
+	case "", JSONOutput:
+	case XMLOutput:
+		if tr.xmlOutput, err = newXMLOutput(schema); err != nil {
+			return nil, fmt.Errorf("failed initializing XML output: %v", err)
+		}
+	default:
+		return nil, fmt.Errorf("unknown output format %s, must be 'JSON' or 'XML'", args.OutputFormat)
+	}
+	if args.PreserveOrder {
+		if tr.propertyOrder, err = newPropertyOrder(schema); err != nil {
+			return nil, fmt.Errorf("failed initializing the property order: %v", err)
+		}
+	}
+	if tr.emitters, err = newEmitters(schema, tr.transformIdentifiers, format, args); err != nil {
 		return nil, err
 	}
 
@@ -85,13 +203,44 @@ func newTransformer(schema *jsonschema.Schema, tranformIdentifier string, format
 // Errors are returned for failures to perform operations but are not returned for empty fields which are either
 // omitted from the output or set to an empty value.
 //
-// Validation of the output against the schema is the final step in the process.
+// Validation of the output against the schema is the final step in the process, when the Transformer has the
+// XMLOutput format the returned data is the validated output written as XML rather than JSON.
 func (tr *Transformer) Transform(raw json.RawMessage) (json.RawMessage, error) {
+	return tr.TransformContext(context.Background(), raw)
+}
+
+// TransformContext is the same as Transform but the context is passed along to any Resolvers used during the
+// transform and can override the Clock with WithClock. The options set the variables, named inputs or metadata of
+// the call, see WithVars, WithInputs and WithMetadata.
+func (tr *Transformer) TransformContext(ctx context.Context, raw json.RawMessage, opts ...CallOption) (json.RawMessage, error) {
+	call, err := tr.newCall(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	return tr.transform(call, raw)
+}
+
+// TransformWithVars is the same as Transform but the variables are available to the transform instructions with
+// `$vars.` paths, see WithVars.
+func (tr *Transformer) TransformWithVars(raw json.RawMessage, vars map[string]interface{}) (json.RawMessage, error) {
+	return tr.TransformContext(context.Background(), raw, WithVars(vars))
+}
+
+// TransformWithMetadata is the same as Transform but also returns the metadata of the call, see WithMetadata. The
+// metadata is returned even when there is an error.
+func (tr *Transformer) TransformWithMetadata(raw json.RawMessage) (json.RawMessage, *TransformMetadata, error) {
+	metadata := &TransformMetadata{}
+	transformed, err := tr.TransformContext(context.Background(), raw, WithMetadata(metadata))
+	return transformed, metadata, err
+}
+
+// transform runs the call with validation returning the output in the output format of the Transformer.
+func (tr *Transformer) transform(call *transformCall, raw json.RawMessage) (json.RawMessage, error) {
 	if tr.format == jsonInput {
-		return tr.jsonTransform(raw)
+		return tr.encodeOutput(tr.jsonTransform(call, raw))
 	}
 	if tr.format == xmlInput {
-		return tr.xmlTransform(raw)
+		return tr.encodeOutput(tr.xmlTransform(call, raw))
 	}
 	return nil, fmt.Errorf("unknown transform type %s, must be 'JSON' or 'XML'", tr.format)
 }
@@ -100,21 +249,34 @@ func (tr *Transformer) Transform(raw json.RawMessage) (json.RawMessage, error) {
 // to test schema transforms, but the schema requires the existence of fields that have to be made beyond the automatic
 // jstransform stage.
 func (tr *Transformer) TransformNoValidation(raw json.RawMessage) (json.RawMessage, error) {
+	call := newTransformCall(context.Background(), tr.args)
 	if tr.format == jsonInput {
-		return tr.baseJSONTransform(raw)
+		return tr.encodeOutput(tr.baseJSONTransform(call, raw))
 	}
 	if tr.format == xmlInput {
-		return tr.baseXMLTransform(raw)
+		return tr.encodeOutput(tr.baseXMLTransform(call, raw))
 	}
 	return nil, fmt.Errorf("unknown transform type %s, must be 'JSON' or 'XML'", tr.format)
 }
 
-func (tr *Transformer) jsonTransform(raw json.RawMessage) (json.RawMessage, error) {
-	transformed, err := tr.baseJSONTransform(raw)
+// encodeOutput writes the transformed JSON in the output format of the Transformer.
+func (tr *Transformer) encodeOutput(transformed json.RawMessage, err error) (json.RawMessage, error) {
+	if err != nil || tr.xmlOutput == nil {
+		return transformed, err
+	}
+	return tr.xmlOutput.encode(transformed, tr.propertyOrder)
+}
+
+func (tr *Transformer) jsonTransform(call *transformCall, raw json.RawMessage) (json.RawMessage, error) {
+	transformed, err := tr.baseJSONTransform(call, raw)
 	if err != nil {
 		return nil, err
 	}
+	return tr.validateJSON(transformed)
+}
 
+// validateJSON returns the transformed JSON if it is valid for the schema.
+func (tr *Transformer) validateJSON(transformed json.RawMessage) (json.RawMessage, error) {
 	valid, err := tr.schema.Validate(transformed)
 	if err != nil {
 		return nil, fmt.Errorf("input successfully transformed but did not match schema: %v", err)
@@ -126,18 +288,26 @@ func (tr *Transformer) jsonTransform(raw json.RawMessage) (json.RawMessage, erro
 	return transformed, nil
 }
 
-func (tr *Transformer) baseJSONTransform(raw json.RawMessage) (json.RawMessage, error) {
-	var in interface{}
-	if err := json.Unmarshal(raw, &in); err != nil {
+func (tr *Transformer) baseJSONTransform(call *transformCall, raw json.RawMessage) (json.RawMessage, error) {
+	in, err := decodeJSON(raw)
+	if err != nil {
 		return nil, fmt.Errorf("failed to parse input JSON: %v", err)
 	}
+	return tr.transformDecoded(call, in)
+}
 
-	transformed, err := tr.root.transform(in, nil)
-	if err != nil {
+// transformDecoded transforms JSON input which is already decoded, without validation. With named inputs the input
+// is also the PrimaryInput.
+func (tr *Transformer) transformDecoded(call *transformCall, in interface{}) (json.RawMessage, error) {
+	if call != nil && call.inputs != nil {
+		call.inputs[PrimaryInput] = in
+	}
+	transformed, err := tr.root.transform(call, in, nil)
+	if err != nil && err != errNullValue {
 		return nil, fmt.Errorf("failed transformation: %v", err)
 	}
 
-	out, err := json.Marshal(transformed)
+	out, err := tr.marshal(transformed)
 	if err != nil {
 		return nil, fmt.Errorf("failed to JSON marsal transformed data: %v", err)
 	}
@@ -145,8 +315,8 @@ func (tr *Transformer) baseJSONTransform(raw json.RawMessage) (json.RawMessage,
 	return out, nil
 }
 
-func (tr *Transformer) xmlTransform(raw []byte) ([]byte, error) {
-	transformedXML, err := tr.baseXMLTransform(raw)
+func (tr *Transformer) xmlTransform(call *transformCall, raw []byte) ([]byte, error) {
+	transformedXML, err := tr.baseXMLTransform(call, raw)
 	if err != nil {
 		return nil, err
 	}
@@ -162,18 +332,18 @@ func (tr *Transformer) xmlTransform(raw []byte) ([]byte, error) {
 	return transformedXML, nil
 }
 
-func (tr *Transformer) baseXMLTransform(raw []byte) ([]byte, error) {
+func (tr *Transformer) baseXMLTransform(call *transformCall, raw []byte) ([]byte, error) {
 	xmlDoc, err := xmlquery.Parse(bytes.NewReader(raw))
 	if err != nil {
 		return nil, fmt.Errorf("failed to parse input XML: %v", err)
 	}
 
-	transformed, err := tr.root.transform(xmlDoc, nil)
-	if err != nil {
+	transformed, err := tr.root.transform(call, xmlDoc, nil)
+	if err != nil && err != errNullValue {
 		return nil, fmt.Errorf("failed transformation: %v", err)
 	}
 
-	out, err := json.Marshal(transformed)
+	out, err := tr.marshal(transformed)
 	if err != nil {
 		return nil, fmt.Errorf("failed to JSON marsal transformed data: %v", err)
 	}
@@ -181,6 +351,14 @@ func (tr *Transformer) baseXMLTransform(raw []byte) ([]byte, error) {
 	return out, nil
 }
 
+// marshal JSON encodes the transformed data, in the order of the schema properties when set.
+func (tr *Transformer) marshal(transformed interface{}) ([]byte, error) {
+	if tr.propertyOrder != nil {
+		return tr.propertyOrder.marshal(transformed)
+	}
+	return json.Marshal(transformed)
+}
+
 // findParent walks the instanceTransformer tree to find the parent of the given path.
 func (tr *Transformer) findParent(path string) (instanceTransformer, error) {
 	path = strings.Replace(path, "[", ".[", -1)
@@ -220,14 +398,14 @@ func (tr *Transformer) walker(path string, value json.RawMessage) error {
 			return fmt.Errorf("failed to extract properties: %v", err)
 		}
 		if string(properties) == "{}" { // Checks for empty "properties"
-			iTransformer, err = newScalarTransformer(path, tr.transformIdentifier, value, instanceType, tr.format)
+			iTransformer, err = newScalarTransformer(path, tr.transformIdentifiers, value, instanceType, tr.format)
 		} else {
-			iTransformer, err = newObjectTransformer(path, tr.transformIdentifier, value, tr.format)
+			iTransformer, err = newObjectTransformer(path, tr.transformIdentifiers, value, tr.format)
 		}
 	case "array":
-		iTransformer, err = newArrayTransformer(path, tr.transformIdentifier, value, tr.format)
+		iTransformer, err = newArrayTransformer(path, tr.transformIdentifiers, value, tr.format)
 	default:
-		iTransformer, err = newScalarTransformer(path, tr.transformIdentifier, value, instanceType, tr.format)
+		iTransformer, err = newScalarTransformer(path, tr.transformIdentifiers, value, instanceType, tr.format)
 	}
 	if err != nil {
 		return fmt.Errorf("failed to initialize transformer: %v", err)
@@ -241,9 +419,62 @@ func (tr *Transformer) walker(path string, value json.RawMessage) error {
 		return err
 	}
 
+	if ot, ok := parent.(*objectTransformer); ok {
+		fev, err := newFieldEmptyValues(value)
+		if err != nil {
+			return err
+		}
+		pathSplits := strings.Split(path, ".")
+		ot.emptyValues[pathSplits[len(pathSplits)-1]] = fev
+		if at, ok := iTransformer.(*arrayTransformer); ok {
+			at.emptyValues = fev
+		}
+	}
+
 	return nil
 }
 
+// linkXMLFallbacks sets the xmlFallback of the arrays and scalars below the given instance. The fallback selects the
+// element at the same path as the field relative to the current node, ie `$.a.b` maps to `a/b`. The current node is
+// the root, an array item or an object with a transform, contextPath is the path of that node.
+func linkXMLFallbacks(it instanceTransformer, contextPath string) {
+	if recorder, ok := it.(*outputRecorder); ok {
+		it = recorder.instanceTransformer
+	}
+	var xmlFallback **transformInstruction
+	switch t := it.(type) {
+	case *arrayTransformer:
+		xmlFallback = &t.xmlFallback
+		linkXMLFallbacks(t.childTransformer, t.jsonPath+"[*]")
+	case *objectTransformer:
+		childContext := contextPath
+		if t.transforms != nil {
+			childContext = t.jsonPath
+		}
+		for _, child := range t.children {
+			linkXMLFallbacks(child, childContext)
+		}
+	case *scalarTransformer:
+		xmlFallback = &t.xmlFallback
+	}
+	if xmlFallback == nil || it.path() == "$" {
+		return
+	}
+
+	relative := strings.TrimPrefix(strings.TrimPrefix(it.path(), contextPath), ".")
+	if relative == "" {
+		*xmlFallback = &transformInstruction{xmlPath: ".", Operations: []transformOperation{}}
+		return
+	}
+	elements := strings.Split(relative, ".")
+	for _, element := range elements {
+		if !xmlNameRe.MatchString(element) {
+			return
+		}
+	}
+	*xmlFallback = &transformInstruction{xmlPath: strings.Join(elements, "/"), Operations: []transformOperation{}}
+}
+
 // saveInTree is used recursively to add values the tree based on the path even if the parents are nil.
 func saveInTree(tree map[string]interface{}, path string, value interface{}) error {
 	if value == nil {
@@ -1,10 +1,12 @@
 package transform
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"os"
 	"reflect"
+	"sort"
 	"testing"
 	"time"
 
@@ -21,6 +23,10 @@ var (
 	frontSchema, _            = jsonschema.SchemaFromFile("./test_data/front.json", "")
 	arrayTransformsSchema2, _ = jsonschema.SchemaFromFile("./test_data/array-transforms-2.json", "")
 	orderedKeysSchema, _      = jsonschema.SchemaFromFile("./test_data/ordered-keys.json", "")
+	resolveSchema, _          = jsonschema.SchemaFromFile("./test_data/resolve.json", "")
+	fallbackSchema, _         = jsonschema.SchemaFromFile("./test_data/fallback-identifiers.json", "")
+	firstValidSchema, _       = jsonschema.SchemaFromFile("./test_data/first-valid.json", "")
+	mergeSchema, _            = jsonschema.SchemaFromFile("./test_data/merge.json", "")
 
 	transformerTests = []struct {
 		description         string
@@ -534,6 +540,767 @@ func TestTransformer(t *testing.T) {
 	}
 }
 
+func TestTransformerResolvers(t *testing.T) {
+	resolver := newMapResolver()
+	tr, err := NewTransformerWithArgs(resolveSchema, "cumulo", TransformerArgs{
+		Resolvers: map[string]Resolver{"authors": resolver},
+	})
+	if err != nil {
+		t.Fatalf("failed to initialize transformer: %v", err)
+	}
+
+	in := json.RawMessage(`{"authorIds": ["1", "2"]}`)
+	want := json.RawMessage(`{"authors":[{"name":"Jane Doe","title":"Reporter"},{"name":"John Doe"}],"byline":"Jane Doe"}`)
+	got, err := tr.TransformContext(context.Background(), in)
+	if err != nil {
+		t.Fatalf("got error, want nil: %v", err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got\n%s\nwant\n%s", got, want)
+	}
+	if resolver.lookups["1"] != 1 || resolver.lookups["2"] != 1 {
+		t.Errorf("got lookups %v, want each key resolved once", resolver.lookups)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := tr.TransformContext(ctx, in); err == nil {
+		t.Error("got nil, want error for a canceled context")
+	}
+
+	noResolvers, err := NewTransformer(resolveSchema, "cumulo")
+	if err != nil {
+		t.Fatalf("failed to initialize transformer: %v", err)
+	}
+	if _, err := noResolvers.Transform(in); err == nil {
+		t.Error("got nil, want error for an unregistered resolver")
+	}
+}
+
+func TestTransformerFallbackIdentifiers(t *testing.T) {
+	in := json.RawMessage(`{"id": "1", "title": "Title", "seoTitle": "SEO Title", "text": "Text"}`)
+
+	tests := []struct {
+		description         string
+		transformIdentifier string
+		fallbacks           []string
+		want                json.RawMessage
+	}{
+		{
+			description:         "no fallbacks",
+			transformIdentifier: "presentationv5",
+			want:                json.RawMessage(`{"id":"1"}`),
+		},
+		{
+			description:         "fallback chain",
+			transformIdentifier: "presentationv5",
+			fallbacks:           []string{"presentationv4", "default"},
+			want:                json.RawMessage(`{"body":"Text","headline":"SEO Title","id":"1"}`),
+		},
+		{
+			description:         "transform identifier takes precedence",
+			transformIdentifier: "default",
+			fallbacks:           []string{"presentationv4"},
+			want:                json.RawMessage(`{"body":"Text","headline":"Title","id":"1"}`),
+		},
+	}
+
+	for _, test := range tests {
+		tr, err := NewTransformerWithArgs(fallbackSchema, test.transformIdentifier, TransformerArgs{FallbackIdentifiers: test.fallbacks})
+		if err != nil {
+			t.Fatalf("Test %q - failed to initialize transformer: %v", test.description, err)
+		}
+		got, err := tr.Transform(in)
+		if err != nil {
+			t.Errorf("Test %q - got error, want nil: %v", test.description, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("Test %q - got\n%s\nwant\n%s", test.description, got, test.want)
+		}
+	}
+}
+
+func TestTransformerExactNumbers(t *testing.T) {
+	schema, err := jsonschema.SchemaFromFile("./test_data/exact-numbers.json", "")
+	if err != nil {
+		t.Fatalf("failed to load schema: %v", err)
+	}
+	tr, err := NewTransformer(schema, "cumulo")
+	if err != nil {
+		t.Fatalf("failed to initialize transformer: %v", err)
+	}
+
+	// The integers of the items compare equal to the number in the filter.
+	in := json.RawMessage(`{"id": 9007199254740993, "ratio": 0.30000000000000000004, "legacy": {"id": "123456789012345678901234"},
+		"items": [{"n": "a", "w": 100}, {"n": "b", "w": 200}]}`)
+	want := json.RawMessage(`{"id":9007199254740993,"idString":"9007199254740993","legacyId":123456789012345678901234,"names":["a"],"ratio":0.30000000000000000004}`)
+
+	got, err := tr.Transform(in)
+	if err != nil {
+		t.Fatalf("got error, want nil: %v", err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestTransformerFirstValid(t *testing.T) {
+	tr, err := NewTransformer(firstValidSchema, "cumulo")
+	if err != nil {
+		t.Fatalf("failed to initialize transformer: %v", err)
+	}
+
+	tests := []struct {
+		description string
+		in          json.RawMessage
+		want        json.RawMessage
+	}{
+		{
+			description: "first candidates valid",
+			in:          json.RawMessage(`{"path": "https://example.com/a", "canonical": "https://example.com/b", "state": "draft", "stars": 4, "team": "NYY"}`),
+			want:        json.RawMessage(`{"code":"NYY","rating":4,"status":"draft","url":"https://example.com/a"}`),
+		},
+		{
+			description: "first candidates invalid",
+			in:          json.RawMessage(`{"path": "/a", "canonical": "https://example.com/b", "state": "live", "legacyState": "published", "stars": 10, "score": 3, "team": "Yankees", "teamCode": "NYY"}`),
+			want:        json.RawMessage(`{"code":"NYY","rating":3,"status":"published","url":"https://example.com/b"}`),
+		},
+		{
+			description: "no valid candidates",
+			in:          json.RawMessage(`{"path": "/a", "state": "live", "stars": 0, "team": "Yankees"}`),
+			want:        json.RawMessage(`null`),
+		},
+	}
+
+	for _, test := range tests {
+		got, err := tr.TransformNoValidation(test.in)
+		if err != nil {
+			t.Errorf("Test %q - got error, want nil: %v", test.description, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("Test %q - got\n%s\nwant\n%s", test.description, got, test.want)
+		}
+	}
+
+	arraySchema, err := jsonschema.SchemaFromFile("./test_data/first-valid-array.json", "")
+	if err != nil {
+		t.Fatalf("failed to load schema: %v", err)
+	}
+	if _, err := NewTransformer(arraySchema, "cumulo"); err == nil {
+		t.Error("got nil, want error for the firstValid method on an array")
+	}
+}
+
+func TestTransformerMerge(t *testing.T) {
+	tr, err := NewTransformer(mergeSchema, "cumulo")
+	if err != nil {
+		t.Fatalf("failed to initialize transformer: %v", err)
+	}
+
+	tests := []struct {
+		description string
+		in          json.RawMessage
+		want        json.RawMessage
+	}{
+		{
+			description: "arrays and objects",
+			in: json.RawMessage(`
+{
+	"taxonomy": {"topics": ["news", "politics"], "places": ["Iowa", "news"], "people": "Jane Doe"},
+	"keywords": ["election"],
+	"seo": {"keywords": ["election", "vote"]},
+	"siteSettings": {"comments": true, "ads": {"enabled": true, "zones": ["top", "side"]}},
+	"storySettings": {"comments": false, "ads": {"zones": ["side", "bottom"]}}
+}`),
+			want: json.RawMessage(`{"keywords":["election","election","vote"],"settings":{"ads":{"enabled":true,"zones":["top","side","bottom"]},"comments":false},"tags":["news","politics","Iowa","Jane Doe"]}`),
+		},
+		{
+			description: "single source",
+			in:          json.RawMessage(`{"seo": {"keywords": ["vote"]}, "storySettings": {"comments": false}}`),
+			want:        json.RawMessage(`{"keywords":["vote"],"settings":{"comments":false}}`),
+		},
+		{
+			description: "uniqueItems of nested arrays",
+			in: json.RawMessage(`
+{
+	"byline": {"authors": ["Jane Doe"], "notes": ["staff"]},
+	"contributors": {"authors": ["Jane Doe", "John Roe"], "notes": ["staff"]}
+}`),
+			want: json.RawMessage(`{"credits":{"authors":["Jane Doe","John Roe"],"notes":["staff","staff"]}}`),
+		},
+	}
+
+	for _, test := range tests {
+		got, err := tr.Transform(test.in)
+		if err != nil {
+			t.Errorf("Test %q - got error, want nil: %v", test.description, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("Test %q - got\n%s\nwant\n%s", test.description, got, test.want)
+		}
+	}
+
+	scalarSchema, err := jsonschema.SchemaFromFile("./test_data/merge-scalar.json", "")
+	if err != nil {
+		t.Fatalf("failed to load schema: %v", err)
+	}
+	if _, err := NewTransformer(scalarSchema, "cumulo"); err == nil {
+		t.Error("got nil, want error for the merge method on a string")
+	}
+}
+
+func TestTransformerOnError(t *testing.T) {
+	schema, err := jsonschema.SchemaFromFile("./test_data/on-error.json", "")
+	if err != nil {
+		t.Fatalf("failed to load schema: %v", err)
+	}
+	tr, err := NewTransformer(schema, "cumulo")
+	if err != nil {
+		t.Fatalf("failed to initialize transformer: %v", err)
+	}
+
+	tests := []struct {
+		description  string
+		in           json.RawMessage
+		want         json.RawMessage
+		wantMetadata *TransformMetadata
+		wantErr      bool
+	}{
+		{
+			description:  "no failures",
+			in:           json.RawMessage(`{"duration": "1:30", "published": "2024-05-01T10:00:00Z", "summary": "c3VtbWFyeQ==", "clips": [{"duration": "0:45"}]}`),
+			want:         json.RawMessage(`{"clips":[{"length":45}],"length":90,"published":"2024-05-01","summary":"SUMMARY"}`),
+			wantMetadata: &TransformMetadata{},
+		},
+		{
+			description: "policies applied",
+			in:          json.RawMessage(`{"duration": "long", "published": "yesterday", "summary": "plain text", "rating": "high", "clips": [{"duration": "0:45"}, {"title": "Trailer", "duration": "short"}]}`),
+			want:        json.RawMessage(`{"clips":[{"length":45},{"length":null,"title":"Trailer"}],"length":0,"published":null,"summary":"PLAIN TEXT"}`),
+			wantMetadata: &TransformMetadata{OperationErrors: []OperationError{
+				{Field: "$.clips[1].length", Path: "$.clips[1].duration", Operation: "duration", Policy: "null", Error: "duration transform input did not match 'MM:SS' or 'HH:MM:SS'"},
+				{Field: "$.length", Path: "$.duration", Operation: "duration", Policy: "default", Error: "duration transform input did not match 'MM:SS' or 'HH:MM:SS'"},
+				{Field: "$.published", Path: "$.published", Operation: "timeParse", Policy: "null", Error: "time could not be parsed using supplied format"},
+				{Field: "$.rating", Path: "$.rating", Operation: "duration", Policy: "null", Error: "duration transform input did not match 'MM:SS' or 'HH:MM:SS'"},
+				{Field: "$.summary", Path: "$.summary", Operation: "base64Decode", Policy: "skip", Error: "failed to base64 decode: illegal base64 data at input byte 5"},
+			}},
+		},
+		{
+			description: "fail policy",
+			in:          json.RawMessage(`{"runtime": "long"}`),
+			wantErr:     true,
+		},
+	}
+
+	for _, test := range tests {
+		got, metadata, err := tr.TransformWithMetadata(test.in)
+
+		switch {
+		case test.wantErr && err != nil:
+			continue
+		case test.wantErr && err == nil:
+			t.Errorf("Test %q - got nil, want error", test.description)
+		case !test.wantErr && err != nil:
+			t.Errorf("Test %q - got error, want nil: %v", test.description, err)
+		case !reflect.DeepEqual(got, test.want):
+			t.Errorf("Test %q - got\n%s\nwant\n%s", test.description, got, test.want)
+		}
+
+		sort.Slice(metadata.OperationErrors, func(i, j int) bool {
+			return metadata.OperationErrors[i].Field < metadata.OperationErrors[j].Field
+		})
+		if !reflect.DeepEqual(metadata, test.wantMetadata) {
+			t.Errorf("Test %q - got metadata %+v, want %+v", test.description, metadata, test.wantMetadata)
+		}
+	}
+
+	defaultSchema, err := jsonschema.SchemaFromFile("./test_data/on-error-default.json", "")
+	if err != nil {
+		t.Fatalf("failed to load schema: %v", err)
+	}
+	if _, err := NewTransformer(defaultSchema, "cumulo"); err == nil {
+		t.Error("got nil, want error for the onError default without a default in the schema")
+	}
+}
+
+func TestTransformerRelativePaths(t *testing.T) {
+	schema, err := jsonschema.SchemaFromFile("./test_data/relative-paths.json", "")
+	if err != nil {
+		t.Fatalf("failed to load schema: %v", err)
+	}
+	tr, err := NewTransformer(schema, "cumulo")
+	if err != nil {
+		t.Fatalf("failed to initialize transformer: %v", err)
+	}
+
+	in := json.RawMessage(`
+{
+	"siteCode": "USAT",
+	"articles": [
+		{"id": "a1", "title": "First", "images": [{"url": "https://example.com/1.jpg"}]},
+		{"id": "a2", "title": "Second", "images": [{"url": "https://example.com/2.jpg"}, {"url": "https://example.com/3.jpg"}]}
+	]
+}`)
+	want := json.RawMessage(`{"articles":[` +
+		`{"id":"a1","images":[{"articleId":"a1","articlePosition":0,"articleTitle":"First","position":"0","siteCode":"USAT","url":"https://example.com/1.jpg"}],"position":0},` +
+		`{"id":"a2","images":[{"articleId":"a2","articlePosition":1,"articleTitle":"Second","position":"0","siteCode":"USAT","url":"https://example.com/2.jpg"},` +
+		`{"articleId":"a2","articlePosition":1,"articleTitle":"Second","position":"1","siteCode":"USAT","url":"https://example.com/3.jpg"}],"position":1}` +
+		`],"siteCode":"USAT"}`)
+
+	got, err := tr.Transform(in)
+	if err != nil {
+		t.Fatalf("got error, want nil: %v", err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got\n%s\nwant\n%s", got, want)
+	}
+
+	for _, path := range []string{"./test_data/relative-paths-root.json", "./test_data/relative-paths-index.json"} {
+		invalidSchema, err := jsonschema.SchemaFromFile(path, "")
+		if err != nil {
+			t.Fatalf("failed to load schema %q: %v", path, err)
+		}
+		if _, err := NewTransformer(invalidSchema, "cumulo"); err == nil {
+			t.Errorf("Schema %q - got nil, want error", path)
+		}
+	}
+}
+
+func TestTransformerVars(t *testing.T) {
+	schema, err := jsonschema.SchemaFromFile("./test_data/vars.json", "")
+	if err != nil {
+		t.Fatalf("failed to load schema: %v", err)
+	}
+	tr, err := NewTransformer(schema, "cumulo")
+	if err != nil {
+		t.Fatalf("failed to initialize transformer: %v", err)
+	}
+
+	tests := []struct {
+		description string
+		in          json.RawMessage
+		vars        map[string]interface{}
+		want        json.RawMessage
+		wantErr     bool
+	}{
+		{
+			description: "paths and operation arguments",
+			in:          json.RawMessage(`{"slug": "-news", "images": [{"url": "https://example.com/1.jpg"}]}`),
+			vars: map[string]interface{}{
+				"siteCode": "USAT",
+				"ingested": time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC),
+				"tenant":   map[string]interface{}{"id": 42},
+				"priority": 3,
+			},
+			want: json.RawMessage(`{"images":[{"site":"USAT","url":"https://example.com/1.jpg"}],"ingested":"2024-05-01T10:00:00Z","priority":3,"siteCode":"USAT","slug":"USAT-news","tenantId":"42"}`),
+		},
+		{
+			description: "missing variables",
+			in:          json.RawMessage(`{}`),
+			vars:        map[string]interface{}{"siteCode": "USAT"},
+			want:        json.RawMessage(`{"siteCode":"USAT"}`),
+		},
+		{
+			description: "missing operation argument",
+			in:          json.RawMessage(`{"slug": "-news"}`),
+			vars:        nil,
+			wantErr:     true,
+		},
+		{
+			description: "variables which can not be encoded",
+			in:          json.RawMessage(`{}`),
+			vars:        map[string]interface{}{"siteCode": func() {}},
+			wantErr:     true,
+		},
+	}
+
+	for _, test := range tests {
+		got, err := tr.TransformWithVars(test.in, test.vars)
+
+		switch {
+		case test.wantErr && err != nil:
+			continue
+		case test.wantErr && err == nil:
+			t.Errorf("Test %q - got nil, want error", test.description)
+		case !test.wantErr && err != nil:
+			t.Errorf("Test %q - got error, want nil: %v", test.description, err)
+		case !reflect.DeepEqual(got, test.want):
+			t.Errorf("Test %q - got\n%s\nwant\n%s", test.description, got, test.want)
+		}
+	}
+}
+
+func TestVarsOperationCache(t *testing.T) {
+	v, err := newVarsOperation("prefix", map[string]string{"value": "$vars.siteCode"})
+	if err != nil {
+		t.Fatalf("failed to initialize operation: %v", err)
+	}
+	call := newTransformCall(context.Background(), TransformerArgs{})
+
+	tests := []struct {
+		siteCode   string
+		in         string
+		want       string
+		wantCached int
+	}{
+		{siteCode: "USAT", in: "a", want: "USATa", wantCached: 1},
+		{siteCode: "USAT", in: "b", want: "USATb", wantCached: 1},
+		{siteCode: "DFP", in: "a", want: "DFPa", wantCached: 2},
+	}
+
+	for _, test := range tests {
+		call.vars = map[string]interface{}{"siteCode": test.siteCode}
+		got, err := call.operate(v, test.in)
+		if err != nil {
+			t.Errorf("Test %q - got error, want nil: %v", test.want, err)
+			continue
+		}
+		if got != test.want {
+			t.Errorf("Test %q - got %v, want %v", test.want, got, test.want)
+		}
+		if cached := len(call.varsOperations[v]); cached != test.wantCached {
+			t.Errorf("Test %q - got %d cached operations, want %d", test.want, cached, test.wantCached)
+		}
+	}
+
+	// An argument from the variables which is invalid fails when run, the error is also kept for the call.
+	changeCase, err := newVarsOperation("changeCase", map[string]string{"to": "$vars.case"})
+	if err != nil {
+		t.Fatalf("failed to initialize operation: %v", err)
+	}
+	call.vars = map[string]interface{}{"case": "title"}
+	for _, in := range []string{"a", "b"} {
+		if _, err := call.operate(changeCase, in); err == nil {
+			t.Errorf("Test %q - got nil, want error for an invalid 'to'", in)
+		}
+	}
+	if cached := len(call.varsOperations[changeCase]); cached != 1 {
+		t.Errorf("got %d cached operations for the invalid 'to', want 1", cached)
+	}
+}
+
+func TestTransformerClock(t *testing.T) {
+	transformerTime := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	callTime := time.Date(2021, 6, 7, 8, 9, 10, 0, time.FixedZone("EDT", -4*60*60))
+
+	tr, err := NewTransformerWithArgs(operationsSchema, "cumulo", TransformerArgs{Clock: FixedClock(transformerTime)})
+	if err != nil {
+		t.Fatalf("failed to initialize transformer: %v", err)
+	}
+	in := transformerTests[0].in
+	pipeline, err := NewPipeline([]PipelineStage{{Version: "v1", Transformer: tr}}, PipelineArgs{})
+	if err != nil {
+		t.Fatalf("failed to initialize pipeline: %v", err)
+	}
+
+	// Each entry point taking a context uses its Clock.
+	entryPoints := map[string]func(ctx context.Context) (json.RawMessage, error){
+		"TransformContext": func(ctx context.Context) (json.RawMessage, error) {
+			return tr.TransformContext(ctx, in, WithMetadata(&TransformMetadata{}))
+		},
+		"TransformFanOutContext": func(ctx context.Context) (json.RawMessage, error) {
+			documents, err := tr.TransformFanOutContext(ctx, in)
+			return documents[RootDocument], err
+		},
+		"Pipeline.TransformContext": func(ctx context.Context) (json.RawMessage, error) {
+			return pipeline.TransformContext(ctx, in)
+		},
+	}
+
+	tests := []struct {
+		description string
+		ctx         context.Context
+		want        string
+	}{
+		{
+			description: "transformer clock",
+			ctx:         context.Background(),
+			want:        "2020-01-02T03:04:05Z",
+		},
+		{
+			description: "call clock",
+			ctx:         WithClock(context.Background(), FixedClock(callTime)),
+			want:        "2021-06-07T08:09:10-04:00",
+		},
+	}
+
+	for _, test := range tests {
+		for name, transform := range entryPoints {
+			got, err := transform(test.ctx)
+			if err != nil {
+				t.Errorf("Test %q - %s got error, want nil: %v", test.description, name, err)
+				continue
+			}
+			var out struct {
+				LastModified string `json:"lastModified"`
+			}
+			if err := json.Unmarshal(got, &out); err != nil {
+				t.Fatalf("Test %q - %s failed to parse output: %v", test.description, name, err)
+			}
+			if out.LastModified != test.want {
+				t.Errorf("Test %q - %s got lastModified %q, want %q", test.description, name, out.LastModified, test.want)
+			}
+		}
+	}
+}
+
+func TestTransformerSnippets(t *testing.T) {
+	schema, err := jsonschema.SchemaFromFile("./test_data/snippets.json", "")
+	if err != nil {
+		t.Fatalf("failed to load schema: %v", err)
+	}
+	tr, err := NewTransformer(schema, "cumulo")
+	if err != nil {
+		t.Fatalf("failed to initialize transformer: %v", err)
+	}
+
+	in := json.RawMessage(`
+{
+	"published": "2024-05-01T10:00:00Z",
+	"dates": {"updated": "2024-05-02T11:00:00Z"},
+	"headline": "Big News",
+	"section": "News",
+	"ssts": {"section": "Sports"},
+	"breaking": true,
+	"slug": "big-news"
+}`)
+	want := json.RawMessage(`{"breaking":"yes","link":"https://www.example.com/story/big-news/","published":"2024-05-01","section":"sports","title":"BIG NEWS","updated":"May 2, 2024"}`)
+
+	got, err := tr.Transform(in)
+	if err != nil {
+		t.Fatalf("got error, want nil: %v", err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got\n%s\nwant\n%s", got, want)
+	}
+
+	missingSchema, err := jsonschema.SchemaFromFile("./test_data/snippets-missing.json", "")
+	if err != nil {
+		t.Fatalf("failed to load schema: %v", err)
+	}
+	if _, err := NewTransformer(missingSchema, "cumulo"); err == nil {
+		t.Error("got nil, want error for a snippet placeholder without a refArgs value")
+	}
+}
+
+func TestTransformerMapping(t *testing.T) {
+	schema, err := jsonschema.SchemaFromFile("./test_data/mapping.json", "")
+	if err != nil {
+		t.Fatalf("failed to load schema: %v", err)
+	}
+	mapping, err := MappingFromFile("./test_data/mapping-overlay.json")
+	if err != nil {
+		t.Fatalf("failed to load mapping: %v", err)
+	}
+	tr, err := NewTransformerWithArgs(schema, "cumulo", TransformerArgs{Mapping: mapping})
+	if err != nil {
+		t.Fatalf("failed to initialize transformer: %v", err)
+	}
+
+	in := json.RawMessage(`
+{
+	"title": "Inline Title",
+	"headline": "Big News",
+	"ssts": {"section": "sports"},
+	"section": "news",
+	"photos": [{"href": "http://example.com/1.jpg"}, {"href": "http://example.com/2.jpg"}]
+}`)
+	want := json.RawMessage(`{"headline":"BIG NEWS","images":[{"url":"http://example.com/1.jpg"},{"url":"http://example.com/2.jpg"}],"section":"sports"}`)
+
+	got, err := tr.Transform(in)
+	if err != nil {
+		t.Fatalf("got error, want nil: %v", err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got\n%s\nwant\n%s", got, want)
+	}
+
+	// The merged section falls back to the inline instructions.
+	got, err = tr.Transform(json.RawMessage(`{"headline": "Big News", "section": "news"}`))
+	if err != nil {
+		t.Fatalf("got error, want nil: %v", err)
+	}
+	if want := `{"headline":"BIG NEWS","section":"news"}`; string(got) != want {
+		t.Errorf("got\n%s\nwant\n%s", got, want)
+	}
+
+	unknown, err := MappingFromFile("./test_data/mapping-unknown.json")
+	if err != nil {
+		t.Fatalf("failed to load mapping: %v", err)
+	}
+	if _, err := NewTransformerWithArgs(schema, "cumulo", TransformerArgs{Mapping: unknown}); err == nil {
+		t.Error("got nil, want error for a mapping path not in the schema")
+	}
+}
+
+func TestTransformerFanOut(t *testing.T) {
+	schema, err := jsonschema.SchemaFromFile("./test_data/fanout/article.json", "")
+	if err != nil {
+		t.Fatalf("failed to load schema: %v", err)
+	}
+	tr, err := NewTransformer(schema, "cumulo")
+	if err != nil {
+		t.Fatalf("failed to initialize transformer: %v", err)
+	}
+
+	tests := []struct {
+		description string
+		in          json.RawMessage
+		want        map[string]string
+		wantErr     bool
+	}{
+		{
+			description: "galleries and video",
+			in: json.RawMessage(`
+{
+	"title": "Big News",
+	"galleries": [
+		{"id": "g1", "name": "Crowds", "photographer": "Jane Doe"},
+		{"id": "g2", "name": "Aftermath"}
+	],
+	"video": {"src": "http://example.com/video.mp4", "duration": 90}
+}`),
+			want: map[string]string{
+				RootDocument: `{"galleryIDs":["g1","g2"],"headline":"Big News"}`,
+				"gallery/g1": `{"credit":"JANE DOE","id":"g1","title":"Crowds"}`,
+				"gallery/g2": `{"id":"g2","title":"Aftermath"}`,
+				"video":      `{"duration":90,"url":"http://example.com/video.mp4"}`,
+			},
+		},
+		{
+			description: "nothing to emit",
+			in:          json.RawMessage(`{"title": "Big News"}`),
+			want: map[string]string{
+				RootDocument: `{"headline":"Big News"}`,
+			},
+		},
+		{
+			description: "invalid child document",
+			in:          json.RawMessage(`{"title": "Big News", "galleries": [{"id": "g1"}]}`),
+			wantErr:     true,
+		},
+		{
+			description: "duplicate keys",
+			in:          json.RawMessage(`{"title": "Big News", "galleries": [{"id": "g1", "name": "a"}, {"id": "g1", "name": "b"}]}`),
+			wantErr:     true,
+		},
+		{
+			description: "invalid root document",
+			in:          json.RawMessage(`{"video": {"src": "http://example.com/video.mp4"}}`),
+			wantErr:     true,
+		},
+	}
+
+	for _, test := range tests {
+		documents, err := tr.TransformFanOut(test.in)
+		switch {
+		case test.wantErr && err != nil:
+			continue
+		case test.wantErr && err == nil:
+			t.Errorf("Test %q - got nil, want error", test.description)
+			continue
+		case !test.wantErr && err != nil:
+			t.Errorf("Test %q - got error, want nil: %v", test.description, err)
+			continue
+		}
+
+		got := make(map[string]string, len(documents))
+		for name, document := range documents {
+			got[name] = string(document)
+		}
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("Test %q - got\n%v\nwant\n%v", test.description, got, test.want)
+		}
+	}
+
+	// Transform only produces the root document.
+	got, err := tr.Transform(json.RawMessage(`{"title": "Big News", "video": {"src": "http://example.com/video.mp4"}}`))
+	if err != nil {
+		t.Fatalf("got error, want nil: %v", err)
+	}
+	if want := `{"headline":"Big News"}`; string(got) != want {
+		t.Errorf("got\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestTransformerMulti(t *testing.T) {
+	schema, err := jsonschema.SchemaFromFile("./test_data/multi-input.json", "")
+	if err != nil {
+		t.Fatalf("failed to load schema: %v", err)
+	}
+	tr, err := NewTransformer(schema, "cumulo")
+	if err != nil {
+		t.Fatalf("failed to initialize transformer: %v", err)
+	}
+
+	story := []byte(`{"headline": "Big News", "section": "news", "assets": [{"assetId": "a1", "caption": "Crowds"}, {"assetId": "a2"}, {"assetId": "a3"}]}`)
+	asset := []byte(`{"images": [{"id": "a1", "url": "http://example.com/1.jpg"}, {"id": "a2", "url": "http://example.com/2.jpg", "caption": "Aftermath"}]}`)
+	taxonomy := []byte(`{"section": {"name": "sports"}}`)
+
+	tests := []struct {
+		description string
+		inputs      map[string][]byte
+		want        string
+		wantErr     bool
+	}{
+		{
+			description: "all inputs",
+			inputs:      map[string][]byte{PrimaryInput: story, "asset": asset, "taxonomy": taxonomy},
+			want:        `{"headline":"Big News","images":[{"caption":"Crowds","id":"a1","url":"http://example.com/1.jpg"},{"caption":"Aftermath","id":"a2","url":"http://example.com/2.jpg"}],"section":"sports"}`,
+		},
+		{
+			description: "missing inputs",
+			inputs:      map[string][]byte{PrimaryInput: story},
+			want:        `{"headline":"Big News","section":"news"}`,
+		},
+		{
+			description: "missing primary input",
+			inputs:      map[string][]byte{"asset": asset, "taxonomy": taxonomy},
+			wantErr:     true,
+		},
+		{
+			description: "invalid input",
+			inputs:      map[string][]byte{PrimaryInput: story, "asset": []byte(`{"images": [`)},
+			wantErr:     true,
+		},
+	}
+
+	for _, test := range tests {
+		got, err := tr.TransformMulti(test.inputs)
+
+		// The same call through the options, with the primary input as the input of the call.
+		if primary, ok := test.inputs[PrimaryInput]; ok {
+			named := make(map[string][]byte)
+			for name, raw := range test.inputs {
+				if name != PrimaryInput {
+					named[name] = raw
+				}
+			}
+			gotContext, errContext := tr.TransformContext(context.Background(), primary, WithInputs(named))
+			if string(gotContext) != string(got) || (errContext == nil) != (err == nil) {
+				t.Errorf("Test %q - got %s, %v with WithInputs, want %s, %v", test.description, gotContext, errContext, got, err)
+			}
+		}
+
+		switch {
+		case test.wantErr && err != nil:
+			continue
+		case test.wantErr && err == nil:
+			t.Errorf("Test %q - got nil, want error", test.description)
+			continue
+		case !test.wantErr && err != nil:
+			t.Errorf("Test %q - got error, want nil: %v", test.description, err)
+			continue
+		}
+
+		if string(got) != test.want {
+			t.Errorf("Test %q - got\n%s\nwant\n%s", test.description, got, test.want)
+		}
+	}
+}
+
 func TestNewXMLTransformer(t *testing.T) {
 	tests := []struct {
 		description         string
@@ -542,6 +1309,7 @@ func TestNewXMLTransformer(t *testing.T) {
 		xmlFilePath         string
 		wantFilePath        string
 		skipValidation      bool
+		args                TransformerArgs
 	}{
 		{
 			description:         "teams NBA",
@@ -628,6 +1396,21 @@ func TestNewXMLTransformer(t *testing.T) {
 			xmlFilePath:         "./test_data/xml/emptyTagTransform.xml",
 			wantFilePath:        "./test_data/xml/emptyTagTransform-out.json",
 		},
+		{
+			description:         "fields without a transform use the element at the same path",
+			transformIdentifier: "test",
+			schemaFilePath:      "./test_data/xml/same-path.json",
+			xmlFilePath:         "./test_data/xml/same-path.xml",
+			wantFilePath:        "./test_data/xml/same-path.out.json",
+		},
+		{
+			description:         "namespaces, attributes and inner XML",
+			transformIdentifier: "mrss",
+			schemaFilePath:      "./test_data/xml/mrss.json",
+			xmlFilePath:         "./test_data/xml/mrss.xml",
+			wantFilePath:        "./test_data/xml/mrss.out.json",
+			args:                TransformerArgs{XMLNamespaces: map[string]string{"media": "http://search.yahoo.com/mrss/"}},
+		},
 	}
 
 	for _, test := range tests {
@@ -636,7 +1419,7 @@ func TestNewXMLTransformer(t *testing.T) {
 			t.Fatalf("Test %q: %v", test.description, err)
 		}
 
-		tr, err := NewXMLTransformer(schema, test.transformIdentifier)
+		tr, err := NewXMLTransformerWithArgs(schema, test.transformIdentifier, test.args)
 		if err != nil {
 			t.Fatalf("Test %q: %v", test.description, err)
 		}
@@ -1,7 +1,9 @@
 package transform
 
 import (
+	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"reflect"
 	"regexp"
@@ -14,6 +16,96 @@ import (
 
 var indexRe = regexp.MustCompile(`\[([\d]+)\]`)
 
+// floatDigits is the number of significant decimal digits a float64 always holds without rounding.
+const floatDigits = 15
+
+// maxFloatInteger is the largest magnitude of the integers a float64 holds exactly, 2^53.
+const maxFloatInteger = 1 << 53
+
+// decodeJSON unmarshals JSON keeping the exact value of numbers. Numbers are decoded as float64, the same as
+// json.Unmarshal, unless they would be rounded. Integers beyond 2^53 are then decoded as int64 and any other numbers
+// which would be rounded are kept as a json.Number.
+func decodeJSON(raw []byte) (interface{}, error) {
+	decoder := json.NewDecoder(bytes.NewReader(raw))
+	decoder.UseNumber()
+
+	var value interface{}
+	if err := decoder.Decode(&value); err != nil {
+		return nil, err
+	}
+	if decoder.More() {
+		return nil, errors.New("invalid character after top-level value")
+	}
+	return exactNumbers(value), nil
+}
+
+// exactNumbers replaces the json.Numbers within the value with an int64 or float64 when they hold the number exactly.
+func exactNumbers(value interface{}) interface{} {
+	switch v := value.(type) {
+	case json.Number:
+		return exactNumber(v)
+	case []interface{}:
+		for i, item := range v {
+			v[i] = exactNumbers(item)
+		}
+	case map[string]interface{}:
+		for key, item := range v {
+			v[key] = exactNumbers(item)
+		}
+	}
+	return value
+}
+
+// exactNumber returns the number as a float64 or int64 if either holds it exactly, otherwise the json.Number.
+// Integers a float64 holds are returned as one so they compare equal to other numbers, ie within jsonPath filters.
+func exactNumber(n json.Number) interface{} {
+	if i, err := n.Int64(); err == nil {
+		if i >= -maxFloatInteger && i <= maxFloatInteger {
+			return float64(i)
+		}
+		return i
+	}
+	if isInteger(n.String()) || significantDigits(n.String()) > floatDigits {
+		return n
+	}
+	if f, err := n.Float64(); err == nil {
+		return f
+	}
+	return n
+}
+
+// isInteger reports if the numeric text has no fraction or exponent.
+func isInteger(number string) bool {
+	return !strings.ContainsAny(number, ".eE")
+}
+
+// significantDigits counts the significant digits of the mantissa of the numeric text.
+func significantDigits(number string) int {
+	if i := strings.IndexAny(number, "eE"); i != -1 {
+		number = number[:i]
+	}
+	digits := strings.Trim(strings.Replace(strings.TrimLeft(number, "-+"), ".", "", 1), "0")
+	return len(digits)
+}
+
+// parseNumber parses numeric text without rounding, returning an int, a float64 or a json.Number for numbers
+// neither holds exactly.
+func parseNumber(number string) (interface{}, error) {
+	if value, err := strconv.Atoi(number); err == nil {
+		return value, nil
+	}
+	value, err := strconv.ParseFloat(number, 64)
+	if err != nil {
+		return nil, err
+	}
+	if json.Valid([]byte(number)) && number == strings.TrimSpace(number) {
+		if exact, ok := exactNumber(json.Number(number)).(json.Number); ok {
+			return exact, nil
+		}
+	}
+	return value, nil
+}
+
 // Concat will combine any two arbitrary values, though only strings are supported for non-trivial concatenation.
 func concat(a, b interface{}, delimiter string) (interface{}, error) {
 	switch {
@@ -42,6 +134,112 @@ func concat(a, b interface{}, delimiter string) (interface{}, error) {
 	}
 }
 
+// uniqueArrays marks the arrays of a field which hold only unique items, ie set with uniqueItems in the field schema.
+// It forms a tree matching the object properties of the schema.
+type uniqueArrays struct {
+	unique     bool
+	properties map[string]*uniqueArrays
+}
+
+// newUniqueArrays builds the uniqueArrays tree from the raw JSON schema of a field, nil is returned when no array
+// within it has uniqueItems set.
+func newUniqueArrays(schema []byte) (*uniqueArrays, error) {
+	var ua uniqueArrays
+	var err error
+	if ua.unique, err = jsonparser.GetBoolean(schema, "uniqueItems"); err != nil && err != jsonparser.KeyPathNotFoundError {
+		return nil, fmt.Errorf("failed to extract uniqueItems: %v", err)
+	}
+
+	err = jsonparser.ObjectEach(schema, func(key []byte, value []byte, dataType jsonparser.ValueType, _ int) error {
+		if dataType != jsonparser.Object {
+			return nil
+		}
+		child, err := newUniqueArrays(value)
+		if err != nil {
+			return fmt.Errorf("property %q: %v", key, err)
+		}
+		if child != nil {
+			if ua.properties == nil {
+				ua.properties = make(map[string]*uniqueArrays)
+			}
+			ua.properties[string(key)] = child
+		}
+		return nil
+	}, "properties")
+	if err != nil && err != jsonparser.KeyPathNotFoundError {
+		return nil, err
+	}
+
+	if !ua.unique && ua.properties == nil {
+		return nil, nil
+	}
+	return &ua, nil
+}
+
+// property returns the uniqueArrays of the named property, nil when it has none.
+func (ua *uniqueArrays) property(name string) *uniqueArrays {
+	if ua == nil {
+		return nil
+	}
+	return ua.properties[name]
+}
+
+// mergeValues deeply merges b into a. Objects are merged key by key and arrays are appended, with only the items of b
+// not already in a added when union is set or unique marks the array. For other values a is kept unless preferB is
+// set.
+func mergeValues(a, b interface{}, preferB, union bool, unique *uniqueArrays) interface{} {
+	switch at := a.(type) {
+	case map[string]interface{}:
+		bt, ok := b.(map[string]interface{})
+		if !ok {
+			break
+		}
+		merged := make(map[string]interface{}, len(at)+len(bt))
+		for key, value := range at {
+			merged[key] = value
+		}
+		for key, value := range bt {
+			if existing, ok := merged[key]; ok && existing != nil {
+				merged[key] = mergeValues(existing, value, preferB, union, unique.property(key))
+				continue
+			}
+			merged[key] = value
+		}
+		return merged
+	case []interface{}:
+		bt, ok := b.([]interface{})
+		if !ok {
+			break
+		}
+		merged := append(append([]interface{}{}, at...), bt...)
+		if !union && (unique == nil || !unique.unique) {
+			return merged
+		}
+		unique := make([]interface{}, 0, len(merged))
+		for _, item := range merged {
+			if !containsValue(unique, item) {
+				unique = append(unique, item)
+			}
+		}
+		return unique
+	}
+
+	if preferB && b != nil {
+		return b
+	}
+	return a
+}
+
+// containsValue reports if an item of the array is deeply equal to the value.
+func containsValue(array []interface{}, value interface{}) bool {
+	for _, item := range array {
+		if reflect.DeepEqual(item, value) {
+			return true
+		}
+	}
+	return false
+}
+
 // convert takes the raw value and checks to see if it matches the jsonType, if not it will attempt to convert it
 // to the correct type. The function does not set defaults so a nil value will be returned as nil not as the desired
 // types empty type.
@@ -85,10 +283,15 @@ func convertBoolean(raw interface{}) (interface{}, error) {
 		return strconv.ParseBool(t)
 	case int:
 		return t > 0, nil
+	case int64:
+		return t > 0, nil
 	case float32:
 		return t > 0, nil
 	case float64:
 		return t > 0, nil
+	case json.Number:
+		value, err := t.Float64()
+		return value > 0, err
 	case nil:
 		return nil, nil
 	default:
@@ -107,20 +310,47 @@ func convertNumber(raw interface{}) (interface{}, error) {
 		if t == "" {
 			return nil, nil
 		}
-		if value, err := strconv.Atoi(t); err == nil {
-			return value, nil
-		}
-		if value, err := strconv.ParseFloat(t, 64); err == nil {
+		if value, err := parseNumber(t); err == nil {
 			return value, nil
 		}
 		return nil, fmt.Errorf("failed to convert string %q to number", t)
-	case int, float32, float64:
+	case int, int64, float32, float64, json.Number:
 		return raw, nil
 	default:
 		return nil, fmt.Errorf("unable to convert type %q to a number", reflect.TypeOf(raw))
 	}
 }
 
+// toFloat64 returns the numeric value of raw as a float64, strings are parsed. An error is returned for any other type.
+func toFloat64(raw interface{}) (float64, error) {
+	if array, ok := raw.([]interface{}); ok && len(array) == 1 {
+		raw = array[0]
+	}
+
+	switch t := raw.(type) {
+	case string:
+		value, err := strconv.ParseFloat(strings.TrimSpace(t), 64)
+		if err != nil {
+			return 0, fmt.Errorf("only supports numeric strings, failed to parse %q", t)
+		}
+		return value, nil
+	case int:
+		return float64(t), nil
+	case int32:
+		return float64(t), nil
+	case int64:
+		return float64(t), nil
+	case float32:
+		return float64(t), nil
+	case float64:
+		return t, nil
+	case json.Number:
+		return t.Float64()
+	default:
+		return 0, fmt.Errorf("only supports numbers and numeric strings, got type %T", raw)
+	}
+}
+
 func convertDateTime(raw interface{}) (interface{}, error) {
 	switch t := raw.(type) {
 	case string:
@@ -140,8 +370,16 @@ func convertDateTime(raw interface{}) (interface{}, error) {
 		return tParsed, nil
 	case int:
 		return time.Unix(int64(t), 0).UTC(), nil
+	case int64:
+		return time.Unix(t, 0).UTC(), nil
 	case float64:
 		return time.Unix(int64(t), 0).UTC(), nil
+	case json.Number:
+		seconds, err := t.Float64()
+		if err != nil {
+			return nil, err
+		}
+		return time.Unix(int64(seconds), 0).UTC(), nil
 	default:
 		return nil, fmt.Errorf("unable to convert type %q to a date-time", reflect.TypeOf(raw))
 	}
@@ -155,6 +393,10 @@ func convertString(raw interface{}) (interface{}, error) {
 		return raw, nil
 	case int:
 		return strconv.Itoa(t), nil
+	case int64:
+		return strconv.FormatInt(t, 10), nil
+	case json.Number:
+		return t.String(), nil
 	case uint64:
 		return strconv.FormatUint(t, 10), nil
 	case float32:
@@ -169,13 +411,20 @@ func convertString(raw interface{}) (interface{}, error) {
 	}
 }
 
-func extractTransformInstructions(raw json.RawMessage, transformIdentifier, path string, instanceType string) (*transformInstructions, error) {
-	rawTransformInstruction, _, _, err := jsonparser.Get(raw, "transform", transformIdentifier)
-	if err != nil && err != jsonparser.KeyPathNotFoundError {
-		return nil, fmt.Errorf("failed to extract raw instance transform: %v", err)
-	} else if len(rawTransformInstruction) == 0 {
+// extractTransformInstructions returns the transform instructions for the first of the transformIdentifiers found in
+// the schema or nil if none are found.
+func extractTransformInstructions(raw json.RawMessage, transformIdentifiers []string, path string, instanceType string) (*transformInstructions, error) {
+	rawTransformInstruction, err := findTransformSection(raw, transformIdentifiers)
+	if err != nil {
+		return nil, err
+	}
+	if len(rawTransformInstruction) == 0 {
 		return nil, nil
 	}
+	rawTransformInstruction, err = substituteRefArgs(rawTransformInstruction)
+	if err != nil {
+		return nil, fmt.Errorf("failed to substitute the refArgs of the transform at %q: %v", path, err)
+	}
 	var parentPath string
 	if instanceType == "scalar" && strings.HasSuffix(path, "[*]") && !strings.HasSuffix(path, ".[*]") {
 		parentPath = path
@@ -188,14 +437,40 @@ func extractTransformInstructions(raw json.RawMessage, transformIdentifier, path
 	if err := json.Unmarshal(rawTransformInstruction, &tis); err != nil {
 		return nil, fmt.Errorf("failed to unmarshal instance transform: %v", err)
 	}
+	if tis.Method == firstValid && instanceType != "scalar" {
+		return nil, fmt.Errorf("the firstValid method is only supported for scalar fields, %q is an %s", path, instanceType)
+	}
+	tis.path = path
+	if err := tis.initSchema(raw); err != nil {
+		return nil, err
+	}
 	// replaces the @. format
 	tis.replaceJSONPathPrefix("@.", parentPath+".")
 	// replaces the @[] format
 	tis.replaceJSONPathPrefix("@[", parentPath+"[")
+	// replaces the ^ and @parent formats
+	if err := tis.resolveRelativePaths(parentPath); err != nil {
+		return nil, err
+	}
 
 	return &tis, nil
 }
 
+// findTransformSection returns the raw transform section for the first of the transformIdentifiers found in the
+// schema or nil if none are found.
+func findTransformSection(raw json.RawMessage, transformIdentifiers []string) (json.RawMessage, error) {
+	for _, transformIdentifier := range transformIdentifiers {
+		rawTransformInstruction, _, _, err := jsonparser.Get(raw, "transform", transformIdentifier)
+		if err != nil && err != jsonparser.KeyPathNotFoundError {
+			return nil, fmt.Errorf("failed to extract raw instance transform: %v", err)
+		}
+		if len(rawTransformInstruction) != 0 {
+			return rawTransformInstruction, nil
+		}
+	}
+	return nil, nil
+}
+
 // schemaDefault determines the default for an instance based on the JSONSchema.
 // If no default is defined nil is returned.
 func schemaDefault(schema json.RawMessage) (interface{}, error) {
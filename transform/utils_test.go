@@ -98,6 +98,72 @@ func TestConcat(t *testing.T) {
 	}
 }
 
+func TestMergeValues(t *testing.T) {
+	tests := []struct {
+		description string
+		a           interface{}
+		b           interface{}
+		preferB     bool
+		union       bool
+		unique      *uniqueArrays
+		want        interface{}
+	}{
+		{
+			description: "append arrays",
+			a:           []interface{}{"a", "b"},
+			b:           []interface{}{"b", "c"},
+			want:        []interface{}{"a", "b", "b", "c"},
+		},
+		{
+			description: "union arrays",
+			a:           []interface{}{"a", "b", "a"},
+			b:           []interface{}{"b", "c"},
+			union:       true,
+			want:        []interface{}{"a", "b", "c"},
+		},
+		{
+			description: "union arrays of objects",
+			a:           []interface{}{map[string]interface{}{"id": "1"}},
+			b:           []interface{}{map[string]interface{}{"id": "1"}, map[string]interface{}{"id": "2"}},
+			union:       true,
+			want:        []interface{}{map[string]interface{}{"id": "1"}, map[string]interface{}{"id": "2"}},
+		},
+		{
+			description: "unique nested arrays",
+			a:           map[string]interface{}{"tags": []interface{}{"a"}, "notes": []interface{}{"a"}},
+			b:           map[string]interface{}{"tags": []interface{}{"a", "b"}, "notes": []interface{}{"a"}},
+			unique:      &uniqueArrays{properties: map[string]*uniqueArrays{"tags": {unique: true}}},
+			want:        map[string]interface{}{"tags": []interface{}{"a", "b"}, "notes": []interface{}{"a", "a"}},
+		},
+		{
+			description: "deep merge objects, first precedence",
+			a:           map[string]interface{}{"a": "1", "nested": map[string]interface{}{"b": "2"}},
+			b:           map[string]interface{}{"a": "3", "c": "4", "nested": map[string]interface{}{"b": "5", "d": "6"}},
+			want:        map[string]interface{}{"a": "1", "c": "4", "nested": map[string]interface{}{"b": "2", "d": "6"}},
+		},
+		{
+			description: "deep merge objects, last precedence",
+			a:           map[string]interface{}{"a": "1", "nested": map[string]interface{}{"b": "2"}},
+			b:           map[string]interface{}{"a": "3", "nested": map[string]interface{}{"b": "5"}},
+			preferB:     true,
+			want:        map[string]interface{}{"a": "3", "nested": map[string]interface{}{"b": "5"}},
+		},
+		{
+			description: "mismatched types",
+			a:           map[string]interface{}{"a": "1"},
+			b:           []interface{}{"a"},
+			preferB:     true,
+			want:        []interface{}{"a"},
+		},
+	}
+
+	for _, test := range tests {
+		if got := mergeValues(test.a, test.b, test.preferB, test.union, test.unique); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("Test %q - got %v, want %v", test.description, got, test.want)
+		}
+	}
+}
+
 func TestConvert(t *testing.T) {
 	tests := []struct {
 		description string
@@ -358,6 +424,48 @@ func TestConvert(t *testing.T) {
 			jsonType:    "number",
 			want:        13,
 		},
+		{
+			description: "str -> integer beyond int64",
+			raw:         "123456789012345678901234",
+			jsonType:    "integer",
+			want:        json.Number("123456789012345678901234"),
+		},
+		{
+			description: "str -> number with more digits than a float64",
+			raw:         "3.14159265358979323846",
+			jsonType:    "number",
+			want:        json.Number("3.14159265358979323846"),
+		},
+		{
+			description: "int64 -> integer",
+			raw:         int64(9007199254740993),
+			jsonType:    "integer",
+			want:        int64(9007199254740993),
+		},
+		{
+			description: "json.Number -> integer",
+			raw:         json.Number("123456789012345678901234"),
+			jsonType:    "integer",
+			want:        json.Number("123456789012345678901234"),
+		},
+		{
+			description: "int64 -> string",
+			raw:         int64(9007199254740993),
+			jsonType:    "string",
+			want:        "9007199254740993",
+		},
+		{
+			description: "json.Number -> string",
+			raw:         json.Number("123456789012345678901234"),
+			jsonType:    "string",
+			want:        "123456789012345678901234",
+		},
+		{
+			description: "int64 -> date-time",
+			raw:         int64(1500000000),
+			jsonType:    "date-time",
+			want:        time.Unix(1500000000, 0).UTC(),
+		},
 	}
 
 	for _, test := range tests {
@@ -375,6 +483,60 @@ func TestConvert(t *testing.T) {
 	}
 }
 
+func TestDecodeJSON(t *testing.T) {
+	tests := []struct {
+		description string
+		raw         string
+		want        interface{}
+		wantErr     bool
+	}{
+		{
+			description: "integers",
+			raw:         `{"small": 1, "large": 9007199254740993, "negative": -9223372036854775808}`,
+			want:        map[string]interface{}{"small": float64(1), "large": int64(9007199254740993), "negative": int64(-9223372036854775808)},
+		},
+		{
+			description: "largest integers held by a float64",
+			raw:         `[9007199254740992, -9007199254740992]`,
+			want:        []interface{}{float64(9007199254740992), float64(-9007199254740992)},
+		},
+		{
+			description: "integer beyond int64",
+			raw:         `[18446744073709551616]`,
+			want:        []interface{}{json.Number("18446744073709551616")},
+		},
+		{
+			description: "floats",
+			raw:         `[1.5, 0.1, 1e3, 123456789.123456]`,
+			want:        []interface{}{1.5, 0.1, float64(1000), 123456789.123456},
+		},
+		{
+			description: "float with more digits than a float64",
+			raw:         `[1.00000000000000011102230246251565]`,
+			want:        []interface{}{json.Number("1.00000000000000011102230246251565")},
+		},
+		{
+			description: "trailing data",
+			raw:         `{} {}`,
+			wantErr:     true,
+		},
+	}
+
+	for _, test := range tests {
+		got, err := decodeJSON([]byte(test.raw))
+		switch {
+		case test.wantErr && err != nil:
+			continue
+		case test.wantErr && err == nil:
+			t.Errorf("Test %q - got nil, want error", test.description)
+		case !test.wantErr && err != nil:
+			t.Errorf("Test %q - got error, want nil: %v", test.description, err)
+		case !reflect.DeepEqual(got, test.want):
+			t.Errorf("Test %q - got %v, want %v", test.description, got, test.want)
+		}
+	}
+}
+
 func TestSchemaDefault(t *testing.T) {
 	tests := []struct {
 		description string
@@ -425,6 +587,66 @@ func TestSchemaDefault(t *testing.T) {
 	}
 }
 
+func TestExtractTransformInstructions(t *testing.T) {
+	schema := json.RawMessage(`
+{
+	"type": "string",
+	"transform": {
+		"default": {"from": [{"jsonPath": "$.default"}]},
+		"presentationv4": {"from": [{"jsonPath": "$.v4"}]},
+		"invalid": {"from": [{"jsonPath": "$.v4"}], "method": "unknown"}
+	}
+}`)
+
+	tests := []struct {
+		description string
+		identifiers []string
+		want        *transformInstructions
+		wantErr     bool
+	}{
+		{
+			description: "first identifier found",
+			identifiers: []string{"presentationv4", "default"},
+			want:        &transformInstructions{From: []*transformInstruction{{jsonPath: "$.v4", Operations: []transformOperation{}}}, path: "$.field"},
+		},
+		{
+			description: "fallback identifier",
+			identifiers: []string{"presentationv5", "presentationv4", "default"},
+			want:        &transformInstructions{From: []*transformInstruction{{jsonPath: "$.v4", Operations: []transformOperation{}}}, path: "$.field"},
+		},
+		{
+			description: "last fallback identifier",
+			identifiers: []string{"presentationv5", "default"},
+			want:        &transformInstructions{From: []*transformInstruction{{jsonPath: "$.default", Operations: []transformOperation{}}}, path: "$.field"},
+		},
+		{
+			description: "no identifier found",
+			identifiers: []string{"presentationv5", "cumulo"},
+			want:        nil,
+		},
+		{
+			description: "invalid transform for the first identifier found",
+			identifiers: []string{"presentationv5", "invalid", "default"},
+			wantErr:     true,
+		},
+	}
+
+	for _, test := range tests {
+		got, err := extractTransformInstructions(schema, test.identifiers, "$.field", "scalar")
+
+		switch {
+		case test.wantErr && err != nil:
+			continue
+		case test.wantErr && err == nil:
+			t.Errorf("Test %q - got nil, want error", test.description)
+		case !test.wantErr && err != nil:
+			t.Errorf("Test %q - got error, want nil: %v", test.description, err)
+		case !reflect.DeepEqual(got, test.want):
+			t.Errorf("Test %q - got %v, want %v", test.description, got, test.want)
+		}
+	}
+}
+
 func TestReplaceIndex(t *testing.T) {
 	tests := []struct {
 		description string
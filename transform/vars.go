@@ -0,0 +1,191 @@
+package transform
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	jsonpath "github.com/GannettDigital/PaesslerAG_jsonpath"
+)
+
+// varsPrefix is the prefix of jsonPaths and operation arguments which read from the variables set with WithVars
+// rather than the input.
+const varsPrefix = "$vars."
+
+// varsOperation is an operation with arguments read from the variables of the call. As the arguments differ between
+// calls the operation is initialized when it is run, once per call for each set of arguments. When the Transformer is
+// created only the arguments which are not read from the variables are checked, by operations which implement
+// staticArgsOperation.
+type varsOperation struct {
+	name   string
+	args   map[string]string
+	schema json.RawMessage
+}
+
+// staticArgsOperation is implemented by transformOperations which can check some of their arguments on their own.
+// The initStatic function is given the arguments which are known when the Transformer is created, those not read from
+// the variables of the call, the rest are checked by init when the variables are resolved.
+type staticArgsOperation interface {
+	initStatic(args map[string]string) error
+}
+
+// initializedOperation is an operation initialized with the arguments of a call, or the error initializing it.
+type initializedOperation struct {
+	op  transformOperation
+	err error
+}
+
+// newVarsOperation returns the varsOperation for the arguments after checking those not read from the variables.
+func newVarsOperation(name string, args map[string]string) (*varsOperation, error) {
+	v := &varsOperation{name: name}
+	if err := v.init(args); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+func (v *varsOperation) init(args map[string]string) error {
+	op, err := newOperation(v.name)
+	if err != nil {
+		return err
+	}
+	v.args = args
+
+	sop, ok := op.(staticArgsOperation)
+	if !ok {
+		return nil
+	}
+	static := make(map[string]string, len(args))
+	for key, arg := range args {
+		if !strings.HasPrefix(arg, varsPrefix) {
+			static[key] = arg
+		}
+	}
+	if err := sop.initStatic(static); err != nil {
+		return fmt.Errorf("failed initializing transform operation: %v", err)
+	}
+	return nil
+}
+
+func (v *varsOperation) initSchema(schema json.RawMessage) error {
+	v.schema = schema
+	return nil
+}
+
+// newOperation returns the operation initialized with the arguments.
+func (v *varsOperation) newOperation(args map[string]string) (transformOperation, error) {
+	op, err := newOperation(v.name)
+	if err != nil {
+		return nil, err
+	}
+	if err := op.init(args); err != nil {
+		return nil, fmt.Errorf("failed initializing transform operation: %v", err)
+	}
+	if sop, ok := op.(schemaOperation); ok && v.schema != nil {
+		if err := sop.initSchema(v.schema); err != nil {
+			return nil, fmt.Errorf("failed initializing transform operation: %v", err)
+		}
+	}
+	return op, nil
+}
+
+func (v *varsOperation) transform(in interface{}) (interface{}, error) {
+	return v.transformWithCall(nil, in)
+}
+
+func (v *varsOperation) transformWithCall(call *transformCall, in interface{}) (interface{}, error) {
+	args := make(map[string]string, len(v.args))
+	for key, arg := range v.args {
+		if !strings.HasPrefix(arg, varsPrefix) {
+			args[key] = arg
+			continue
+		}
+		value := call.variable("$" + strings.TrimPrefix(arg, "$vars"))
+		if value == nil {
+			return nil, fmt.Errorf("no variable found for the argument %q of %q", key, arg)
+		}
+		text, err := convertString(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid variable for the argument %q of %q: %v", key, arg, err)
+		}
+		args[key] = text.(string)
+	}
+
+	op, err := call.varsOperation(v, args)
+	if err != nil {
+		return nil, err
+	}
+	return call.operate(op, in)
+}
+
+// varsOperation returns the operation initialized with the arguments. The operation, or the error initializing it, is
+// kept for the rest of the call so the arguments are only checked once for each set of variables.
+func (call *transformCall) varsOperation(v *varsOperation, args map[string]string) (transformOperation, error) {
+	if call == nil {
+		return v.newOperation(args)
+	}
+	key, err := json.Marshal(args)
+	if err != nil {
+		return nil, fmt.Errorf("failed to JSON encode the arguments: %v", err)
+	}
+	if initialized, ok := call.varsOperations[v][string(key)]; ok {
+		return initialized.op, initialized.err
+	}
+
+	op, err := v.newOperation(args)
+	if call.varsOperations == nil {
+		call.varsOperations = make(map[*varsOperation]map[string]initializedOperation)
+	}
+	if call.varsOperations[v] == nil {
+		call.varsOperations[v] = make(map[string]initializedOperation)
+	}
+	call.varsOperations[v][string(key)] = initializedOperation{op: op, err: err}
+	return op, err
+}
+
+// WithVars makes the variables available to the transform instructions of the call with `$vars.` paths, ie
+// `$vars.siteCode`, both as jsonPaths and as the arguments of operations.
+func WithVars(vars map[string]interface{}) CallOption {
+	return func(call *transformCall) error {
+		var err error
+		call.vars, err = newVars(vars)
+		return err
+	}
+}
+
+// hasVarsArgs reports if any of the operation arguments read from the variables of the call.
+func hasVarsArgs(args map[string]string) bool {
+	for _, arg := range args {
+		if strings.HasPrefix(arg, varsPrefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// newVars returns the variables with the values as they would be decoded from JSON, so they are handled the same as
+// input values, ie a time.Time is a string and an int is an int64.
+func newVars(vars map[string]interface{}) (map[string]interface{}, error) {
+	raw, err := json.Marshal(vars)
+	if err != nil {
+		return nil, fmt.Errorf("failed to JSON encode the variables: %v", err)
+	}
+	decoded, err := decodeJSON(raw)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse the variables: %v", err)
+	}
+	normalized, _ := decoded.(map[string]interface{})
+	return normalized, nil
+}
+
+// variable returns the value at the jsonPath within the variables of the call or nil if there is none.
+func (call *transformCall) variable(path string) interface{} {
+	if call == nil || call.vars == nil {
+		return nil
+	}
+	value, err := jsonpath.Get(path, call.vars)
+	if err != nil {
+		return nil
+	}
+	return value
+}
@@ -0,0 +1,359 @@
+package transform
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/GannettDigital/jsonparser"
+	"github.com/GannettDigital/jstransform/jsonschema"
+)
+
+// OutputFormat selects the encoding of the data returned by a Transformer, the options are 'JSON' or 'XML'.
+type OutputFormat string
+
+const (
+	JSONOutput = OutputFormat("JSON")
+	XMLOutput  = OutputFormat("XML")
+)
+
+// defaultXMLRootName is the name of the root element when the schema has no xml name for it.
+const defaultXMLRootName = "root"
+
+// xmlOutputNameRe matches the element and attribute names and the namespace prefixes allowed in XML output. Prefixed
+// names are built from the prefix of the xml object so the namespace is always declared.
+var xmlOutputNameRe = regexp.MustCompile(`^[A-Za-z_][\w.-]*$`)
+
+// xmlAnnotation is the `xml` object of a schema instance which mirrors the XML object of OpenAPI.
+type xmlAnnotation struct {
+	Name      string `json:"name"`
+	Namespace string `json:"namespace"`
+	Prefix    string `json:"prefix"`
+	Attribute bool   `json:"attribute"`
+	Wrapped   bool   `json:"wrapped"`
+}
+
+// xmlNode describes how an instance of the schema is written as XML, it forms a tree matching the schema.
+type xmlNode struct {
+	name         string // The qualified name, including any prefix
+	namespace    string
+	prefix       string
+	attribute    bool
+	wrapped      bool
+	instanceType string
+	items        *xmlNode
+	properties   map[string]*xmlNode
+	declarations []xml.Attr // The namespace declarations written on the element of the node
+}
+
+// newXMLNode builds the xmlNode for a schema instance, defaultName is used when the xml annotation has no name.
+func newXMLNode(raw json.RawMessage, instanceType, defaultName string) (*xmlNode, error) {
+	var annotation xmlAnnotation
+	if len(raw) > 0 {
+		if err := json.Unmarshal(raw, &annotation); err != nil {
+			return nil, fmt.Errorf("failed to parse the xml object: %v", err)
+		}
+	}
+
+	node := &xmlNode{
+		name:         annotation.Name,
+		namespace:    annotation.Namespace,
+		prefix:       annotation.Prefix,
+		attribute:    annotation.Attribute,
+		wrapped:      annotation.Wrapped,
+		instanceType: instanceType,
+	}
+	if node.name == "" {
+		node.name = defaultName
+	}
+	if !xmlOutputNameRe.MatchString(node.name) {
+		return nil, fmt.Errorf("%q is not a valid XML name, set the name of the xml object and use the prefix for namespaces", node.name)
+	}
+	if node.prefix != "" {
+		if !xmlOutputNameRe.MatchString(node.prefix) || strings.HasPrefix(strings.ToLower(node.prefix), "xml") {
+			return nil, fmt.Errorf("%q is not a valid XML namespace prefix", node.prefix)
+		}
+		if node.namespace == "" {
+			return nil, fmt.Errorf("the prefix %q has no namespace", node.prefix)
+		}
+		node.name = node.prefix + ":" + node.name
+	} else if node.namespace != "" {
+		if node.attribute {
+			return nil, errors.New("attributes with a namespace must have a prefix")
+		}
+		// Without a prefix the namespace is the default namespace of the element.
+		node.declarations = []xml.Attr{{Name: xml.Name{Local: "xmlns"}, Value: node.namespace}}
+	}
+	if node.attribute && (instanceType == "object" || instanceType == "array") {
+		return nil, fmt.Errorf("only scalar fields can be attributes not %s fields", instanceType)
+	}
+	if node.wrapped && instanceType != "array" {
+		return nil, fmt.Errorf("only array fields can be wrapped not %s fields", instanceType)
+	}
+	return node, nil
+}
+
+// newXMLOutput builds the xmlNode tree used to write the transformed data of the schema as XML.
+func newXMLOutput(schema *jsonschema.Schema) (*xmlNode, error) {
+	rootType := "object"
+	if schema.Properties == nil {
+		rootType = "array"
+	}
+	root, err := newXMLNode(schema.XML, rootType, defaultXMLRootName)
+	if err != nil {
+		return nil, fmt.Errorf("invalid xml object for the root: %v", err)
+	}
+	// The root array always has an element wrapping the items.
+	root.wrapped = rootType == "array"
+
+	if err := jsonschema.WalkRaw(schema, func(path string, value json.RawMessage) error {
+		return root.addDescendant(path, value)
+	}); err != nil {
+		return nil, err
+	}
+	if root.instanceType == "array" && root.items == nil {
+		return nil, fmt.Errorf("no items found for the root array")
+	}
+
+	// The prefixed namespaces are all declared on the root element so each is declared once.
+	namespaces := make(map[string]string)
+	if err := root.collectNamespaces(namespaces); err != nil {
+		return nil, err
+	}
+	prefixes := make([]string, 0, len(namespaces))
+	for prefix := range namespaces {
+		prefixes = append(prefixes, prefix)
+	}
+	sort.Strings(prefixes)
+	for _, prefix := range prefixes {
+		root.declarations = append(root.declarations, xml.Attr{Name: xml.Name{Local: "xmlns:" + prefix}, Value: namespaces[prefix]})
+	}
+	return root, nil
+}
+
+// collectNamespaces adds the prefixed namespaces of the node and its descendants, a prefix can only be bound to
+// one namespace.
+func (node *xmlNode) collectNamespaces(namespaces map[string]string) error {
+	if node == nil {
+		return nil
+	}
+	if node.prefix != "" {
+		if namespace, ok := namespaces[node.prefix]; ok && namespace != node.namespace {
+			return fmt.Errorf("the prefix %q is used for both the namespaces %q and %q", node.prefix, namespace, node.namespace)
+		}
+		namespaces[node.prefix] = node.namespace
+	}
+	if err := node.items.collectNamespaces(namespaces); err != nil {
+		return err
+	}
+	for _, child := range node.properties {
+		if err := child.collectNamespaces(namespaces); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// addDescendant adds the xmlNode for the schema instance at path to the tree, the parent must already be present.
+func (root *xmlNode) addDescendant(path string, value json.RawMessage) error {
+	splits := strings.Split(strings.Replace(path, "[", ".[", -1), ".")
+	parent := root
+	for _, sp := range splits[1 : len(splits)-1] {
+		if sp == "[*]" {
+			parent = parent.items
+		} else {
+			parent = parent.properties[sp]
+		}
+		if parent == nil {
+			return fmt.Errorf("no parent found for %q", path)
+		}
+	}
+
+	instanceType, _, err := jsonschema.FieldType(value)
+	if err != nil {
+		return fmt.Errorf("failed to extract instance type: %v", err)
+	}
+	annotation, _, _, err := jsonparser.Get(value, "xml")
+	if err != nil && err != jsonparser.KeyPathNotFoundError {
+		return fmt.Errorf("failed to extract the xml object at %q: %v", path, err)
+	}
+
+	name := splits[len(splits)-1]
+	if name == "[*]" {
+		if parent == root {
+			name = "item"
+		} else {
+			// Like OpenAPI the items are named after the array unless they have their own name.
+			name = parent.name
+		}
+	}
+	node, err := newXMLNode(annotation, instanceType, name)
+	if err != nil {
+		return fmt.Errorf("invalid xml object at %q: %v", path, err)
+	}
+
+	if splits[len(splits)-1] == "[*]" {
+		if node.attribute {
+			return fmt.Errorf("invalid xml object at %q: array items can not be attributes", path)
+		}
+		parent.items = node
+		return nil
+	}
+	if parent.properties == nil {
+		parent.properties = make(map[string]*xmlNode)
+	}
+	parent.properties[name] = node
+	return nil
+}
+
+// encode writes the transformed JSON as XML, the elements of objects are in the property order when it is set.
+func (root *xmlNode) encode(transformed json.RawMessage, order *propertyOrder) ([]byte, error) {
+	in, err := decodeJSON(transformed)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse transformed JSON: %v", err)
+	}
+
+	buf := bytes.NewBufferString(xml.Header)
+	enc := xml.NewEncoder(buf)
+	if err := root.encodeValue(enc, root.name, in, order); err != nil {
+		return nil, fmt.Errorf("failed to XML encode transformed data: %v", err)
+	}
+	if err := enc.Flush(); err != nil {
+		return nil, fmt.Errorf("failed to XML encode transformed data: %v", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// encodeValue writes the value as elements with the given name. The node is nil for values which are not described
+// by the schema, ie additional properties, in which case the keys of objects are used as the element names.
+func (node *xmlNode) encodeValue(enc *xml.Encoder, name string, value interface{}, order *propertyOrder) error {
+	switch v := value.(type) {
+	case nil:
+		return nil
+	case []interface{}:
+		return node.encodeArray(enc, name, v, order)
+	case map[string]interface{}:
+		return node.encodeObject(enc, name, v, order)
+	}
+
+	text, err := xmlOutputText(value)
+	if err != nil {
+		return err
+	}
+	start := node.startElement(name)
+	if err := enc.EncodeToken(start); err != nil {
+		return err
+	}
+	if err := enc.EncodeToken(xml.CharData(text)); err != nil {
+		return err
+	}
+	return enc.EncodeToken(start.End())
+}
+
+func (node *xmlNode) encodeArray(enc *xml.Encoder, name string, values []interface{}, order *propertyOrder) error {
+	var items *xmlNode
+	itemName := name
+	if node != nil && node.items != nil {
+		items = node.items
+		itemName = items.name
+	}
+
+	if node == nil || !node.wrapped {
+		for _, value := range values {
+			if err := items.encodeValue(enc, itemName, value, order.itemsOrder()); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	start := node.startElement(name)
+	if err := enc.EncodeToken(start); err != nil {
+		return err
+	}
+	for _, value := range values {
+		if err := items.encodeValue(enc, itemName, value, order.itemsOrder()); err != nil {
+			return err
+		}
+	}
+	return enc.EncodeToken(start.End())
+}
+
+func (node *xmlNode) encodeObject(enc *xml.Encoder, name string, values map[string]interface{}, order *propertyOrder) error {
+	keys := order.orderKeys(values)
+
+	start := node.startElement(name)
+	var children []string
+	for _, key := range keys {
+		child := node.property(key)
+		if child == nil || !child.attribute {
+			children = append(children, key)
+			continue
+		}
+		if values[key] == nil {
+			continue
+		}
+		text, err := xmlOutputText(values[key])
+		if err != nil {
+			return fmt.Errorf("attribute %q: %v", child.name, err)
+		}
+		start.Attr = append(start.Attr, xml.Attr{Name: xml.Name{Local: child.name}, Value: text})
+	}
+
+	if err := enc.EncodeToken(start); err != nil {
+		return err
+	}
+	for _, key := range children {
+		child := node.property(key)
+		childName := key
+		if child != nil {
+			childName = child.name
+		} else if !xmlOutputNameRe.MatchString(key) {
+			return fmt.Errorf("the key %q is not a valid XML name", key)
+		}
+		if err := child.encodeValue(enc, childName, values[key], order.property(key)); err != nil {
+			return err
+		}
+	}
+	return enc.EncodeToken(start.End())
+}
+
+// startElement returns the start of an element with the given name and the namespace declarations of the node.
+func (node *xmlNode) startElement(name string) xml.StartElement {
+	start := xml.StartElement{Name: xml.Name{Local: name}}
+	if node != nil && len(node.declarations) > 0 {
+		start.Attr = append(start.Attr, node.declarations...)
+	}
+	return start
+}
+
+// property returns the node of the named property or nil if it is not in the schema.
+func (node *xmlNode) property(name string) *xmlNode {
+	if node == nil {
+		return nil
+	}
+	return node.properties[name]
+}
+
+// xmlOutputText formats a scalar value as the text of an element or attribute.
+func xmlOutputText(value interface{}) (string, error) {
+	switch v := value.(type) {
+	case string:
+		return v, nil
+	case int64:
+		return strconv.FormatInt(v, 10), nil
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64), nil
+	case bool:
+		return strconv.FormatBool(v), nil
+	case json.Number:
+		return v.String(), nil
+	}
+	return "", fmt.Errorf("unable to write %T as XML text", value)
+}
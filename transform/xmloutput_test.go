@@ -0,0 +1,172 @@
+package transform
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/GannettDigital/jstransform/jsonschema"
+)
+
+func TestXMLOutput(t *testing.T) {
+	schema, err := jsonschema.SchemaFromFile("./test_data/xml-output.json", "")
+	if err != nil {
+		t.Fatalf("failed to load schema: %v", err)
+	}
+	tr, err := NewTransformerWithArgs(schema, "syndication", TransformerArgs{OutputFormat: XMLOutput})
+	if err != nil {
+		t.Fatalf("failed to initialize transformer: %v", err)
+	}
+
+	tests := []struct {
+		description string
+		in          json.RawMessage
+		want        string
+		wantErr     bool
+	}{
+		{
+			description: "attributes, wrapped and unwrapped arrays",
+			in: json.RawMessage(`
+{
+	"version": "2.0",
+	"headline": "News & Sports",
+	"keywords": ["news", "sports"],
+	"stories": [
+		{"id": 1, "link": "https://example.com/1", "premium": true, "thumbnail": {"url": "https://example.com/1.jpg", "width": 640}},
+		{"id": 2, "link": "https://example.com/2"}
+	]
+}`),
+			want: `<?xml version="1.0" encoding="UTF-8"?>` + "\n" +
+				`<channel xmlns:media="http://search.yahoo.com/mrss/" version="2.0"><keywords><keyword>news</keyword><keyword>sports</keyword></keywords>` +
+				`<item id="1"><link>https://example.com/1</link><isPremium>true</isPremium>` +
+				`<media:thumbnail url="https://example.com/1.jpg" width="640"></media:thumbnail></item>` +
+				`<item id="2"><link>https://example.com/2</link></item>` +
+				`<title>News &amp; Sports</title></channel>`,
+		},
+		{
+			description: "empty fields",
+			in:          json.RawMessage(`{"headline": "Title"}`),
+			want: `<?xml version="1.0" encoding="UTF-8"?>` + "\n" +
+				`<channel xmlns:media="http://search.yahoo.com/mrss/"><title>Title</title></channel>`,
+		},
+		{
+			description: "validated before encoding",
+			in:          json.RawMessage(`{"stories": [{"link": "https://example.com/1"}]}`),
+			wantErr:     true,
+		},
+	}
+
+	for _, test := range tests {
+		got, err := tr.Transform(test.in)
+
+		switch {
+		case test.wantErr && err != nil:
+			continue
+		case test.wantErr && err == nil:
+			t.Errorf("Test %q - got nil, want error", test.description)
+		case !test.wantErr && err != nil:
+			t.Errorf("Test %q - got error, want nil: %v", test.description, err)
+		case string(got) != test.want:
+			t.Errorf("Test %q - got\n%s\nwant\n%s", test.description, got, test.want)
+		}
+	}
+}
+
+func TestXMLOutputOrder(t *testing.T) {
+	schema, err := jsonschema.SchemaFromFile("./test_data/xml-output-order.json", "")
+	if err != nil {
+		t.Fatalf("failed to load schema: %v", err)
+	}
+	in := json.RawMessage(`{"title": "Feed", "entries": [{"title": "First", "id": "1"}]}`)
+
+	tests := []struct {
+		description   string
+		preserveOrder bool
+		want          string
+	}{
+		{
+			description: "alphabetical",
+			want: `<?xml version="1.0" encoding="UTF-8"?>` + "\n" +
+				`<feed xmlns="http://www.w3.org/2005/Atom"><entry><id>1</id><title>First</title></entry>` +
+				`<title>Feed</title></feed>`,
+		},
+		{
+			description:   "preserve order",
+			preserveOrder: true,
+			want: `<?xml version="1.0" encoding="UTF-8"?>` + "\n" +
+				`<feed xmlns="http://www.w3.org/2005/Atom"><title>Feed</title><entry><title>First</title><id>1</id></entry></feed>`,
+		},
+	}
+
+	for _, test := range tests {
+		tr, err := NewTransformerWithArgs(schema, "syndication", TransformerArgs{OutputFormat: XMLOutput, PreserveOrder: test.preserveOrder})
+		if err != nil {
+			t.Fatalf("Test %q - failed to initialize transformer: %v", test.description, err)
+		}
+		got, err := tr.Transform(in)
+		switch {
+		case err != nil:
+			t.Errorf("Test %q - got error, want nil: %v", test.description, err)
+		case string(got) != test.want:
+			t.Errorf("Test %q - got\n%s\nwant\n%s", test.description, got, test.want)
+		}
+	}
+}
+
+func TestXMLOutputInvalid(t *testing.T) {
+	tests := []struct {
+		description  string
+		schemaPath   string
+		outputFormat OutputFormat
+	}{
+		{
+			description:  "object attribute",
+			schemaPath:   "./test_data/xml-output-attribute.json",
+			outputFormat: XMLOutput,
+		},
+		{
+			description:  "invalid element name",
+			schemaPath:   "./test_data/xml-output-name.json",
+			outputFormat: XMLOutput,
+		},
+		{
+			description:  "prefix in the element name",
+			schemaPath:   "./test_data/xml-output-prefixed-name.json",
+			outputFormat: XMLOutput,
+		},
+		{
+			description:  "prefix without a namespace",
+			schemaPath:   "./test_data/xml-output-prefix.json",
+			outputFormat: XMLOutput,
+		},
+		{
+			description:  "prefix used for two namespaces",
+			schemaPath:   "./test_data/xml-output-prefix-conflict.json",
+			outputFormat: XMLOutput,
+		},
+		{
+			description:  "attribute namespace without a prefix",
+			schemaPath:   "./test_data/xml-output-namespace-attribute.json",
+			outputFormat: XMLOutput,
+		},
+		{
+			description:  "wrapped scalar",
+			schemaPath:   "./test_data/xml-output-wrapped.json",
+			outputFormat: XMLOutput,
+		},
+		{
+			description:  "unknown output format",
+			schemaPath:   "./test_data/xml-output.json",
+			outputFormat: OutputFormat("YAML"),
+		},
+	}
+
+	for _, test := range tests {
+		schema, err := jsonschema.SchemaFromFile(test.schemaPath, "")
+		if err != nil {
+			t.Fatalf("Test %q - failed to load schema: %v", test.description, err)
+		}
+		if _, err := NewTransformerWithArgs(schema, "syndication", TransformerArgs{OutputFormat: test.outputFormat}); err == nil {
+			t.Errorf("Test %q - got nil, want error", test.description)
+		}
+	}
+}